@@ -1,59 +1,257 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"Backend/internal/apierror"
+	"Backend/internal/cleanup"
+	"Backend/internal/config"
 	"Backend/internal/db"
+	"Backend/internal/handlers"
+	"Backend/internal/health"
+	"Backend/internal/links"
+	"Backend/internal/mail"
+	"Backend/internal/metrics"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
 	"Backend/internal/routes"
+	"Backend/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load env
-	if err := godotenv.Load(".env"); err != nil {
-		_ = godotenv.Load("../../.env")
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Loading configuration failed: %v", err)
 	}
 
+	// "server migrate ..." only needs the database
+	migrate := len(os.Args) > 1 && os.Args[1] == "migrate"
+	if !migrate {
+		if err := cfg.Validate(); err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
+	}
+
+	// Route all logging, including the log package, through slog
+	logger := newLogger(cfg)
+	slog.SetDefault(logger)
+
 	// Connect DB
-	if err := db.ConnectDB(); err != nil {
+	if err := db.ConnectDB(cfg.DatabaseURL, cfg.DatabasePool); err != nil {
 		log.Fatalf("DB connection failed: %v", err)
 	}
 	defer db.CloseDB()
 
+	// "server migrate ..." manages the schema and exits
+	if migrate {
+		if err := runMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Optionally bring the schema up to date before serving
+	if cfg.MigrateOnStart {
+		n, err := db.MigrateUp(context.Background(), db.DB)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Printf("Applied %d migration(s)", n)
+	}
+
+	// Select storage backend
+	backend, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Storage initialization failed: %v", err)
+	}
+	store := metrics.InstrumentStorage(backend, cfg.Storage.Name())
+	if err := metrics.RegisterDB(db.DB, "postgres"); err != nil {
+		log.Fatalf("Metrics initialization failed: %v", err)
+	}
+
+	// Select mail transport
+	transport, err := mail.NewTransport(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Mail initialization failed: %v", err)
+	}
+	mailer, err := mail.New(transport, cfg.Mail.Sender)
+	if err != nil {
+		log.Fatalf("Mail initialization failed: %v", err)
+	}
+
+	// Wire handlers to their dependencies
+	repos := repository.NewPostgres(db.DB)
+	cleaner := cleanup.New(store, repos)
+	jwtSecret := []byte(cfg.JWTSecret)
+	verification, err := handlers.ParseVerificationPolicy(cfg.EmailVerificationRequiredFor)
+	if err != nil {
+		log.Fatalf("Invalid EMAIL_VERIFICATION_REQUIRED_FOR: %v", err)
+	}
+	linkBuilder, err := links.New(cfg.FrontendURL)
+	if err != nil {
+		log.Fatalf("Invalid FRONTEND_URL: %v", err)
+	}
+	if cfg.DevMode() {
+		log.Printf("Development mode: password reset links are included in API responses")
+	}
+	readiness := health.New(
+		health.Check{Name: "postgres", Run: db.DB.PingContext},
+		// Storage and SMTP probes hit remote services; don't repeat them on every poll
+		health.Check{Name: "storage", Run: store.Ping, CacheFor: time.Minute},
+		health.Check{Name: "mail", Run: mailer.Check, CacheFor: time.Minute},
+	)
+	h := handlers.New(handlers.Deps{
+		Repos:        repos,
+		Storage:      store,
+		Cleaner:      cleaner,
+		Mailer:       mailer,
+		JWTSecret:    jwtSecret,
+		Verification: verification,
+		Links:        linkBuilder,
+		DevMode:      cfg.DevMode(),
+		Readiness:    readiness,
+		Uploads: handlers.UploadLimits{
+			MaxFileBytes:    cfg.Uploads.MaxFileBytes,
+			MaxRequestBytes: cfg.Uploads.MaxRequestBytes,
+			Concurrency:     cfg.Uploads.Concurrency,
+		},
+	})
+	auth := middleware.NewAuthMiddleware(jwtSecret, repos.Sessions)
+
+	// Retry remote asset deletions that failed inline
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go cleaner.RunSweeper(sweeperCtx, 5*time.Minute)
+
+	// QR codes printed before upload links existed encode a URL that no
+	// longer accepts uploads; replace them without holding up startup
+	go func() {
+		n, err := h.ReissueLegacyQRCodes(sweeperCtx)
+		if err != nil {
+			logger.Error("reissue legacy QR codes", "reissued", n, "error", err)
+		} else if n > 0 {
+			logger.Info("reissued legacy QR codes", "reissued", n)
+		}
+	}()
+
 	// Set up router
 	r := chi.NewRouter()
+	r.Use(middleware.NewRequestLogger(logger))
+	r.Use(metrics.Middleware)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // or "*" to allow all
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Deletion-Token", middleware.RequestIDHeader},
+		ExposedHeaders:   []string{"Link", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any major browsers
 	}))
 
-	// Basic health route
+	// Liveness and readiness probes
+	routes.RegisterHealthRoutes(r, h)
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, "Server is running and connected to Supabase!")
+		fmt.Fprintln(w, "Server is running")
+	})
+
+	// Serve files written by the local storage backend
+	if local, ok := backend.(*storage.Local); ok {
+		r.Handle("/files/*", http.StripPrefix("/files", local))
+	}
+
+	// Unknown routes answer with the same error body as the API
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Respond(w, apierror.RouteNotFound, "Not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Respond(w, apierror.MethodNotAllowed, "Method not allowed")
 	})
 
 	// Register routes
 	r.Route("/api", func(api chi.Router) {
-		routes.RegisterAuthRoutes(api)
-		routes.RegisterImageRoutes(api)
-		routes.RegisterFolderRoutes(api)
+		routes.RegisterAuthRoutes(api, h, auth)
+		routes.RegisterImageRoutes(api, h, auth)
+		routes.RegisterFolderRoutes(api, h, auth)
 	})
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server started on http://localhost:%s", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Metrics are unauthenticated, so they get a listener of their own,
+	// bound to loopback unless METRICS_ADDR says otherwise
+	var metricsSrv *http.Server
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsSrv = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+		go func() {
+			log.Printf("Metrics served on %s/metrics", cfg.MetricsAddr)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("metrics listener: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Stop accepting connections and let in-flight requests, such as uploads,
+	// finish before the database is closed
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown incomplete: %v", err)
+		_ = srv.Close()
+	}
+	if metricsSrv != nil {
+		_ = metricsSrv.Close()
+	}
+	stopSweeper()
+	log.Printf("Server stopped")
+}
+
+// newLogger builds the process logger from the configured level and format.
+func newLogger(cfg config.Config) *slog.Logger {
+	level, _ := cfg.SlogLevel()
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "text" {
+		return slog.New(slog.NewTextHandler(os.Stderr, opts))
 	}
-	log.Printf("Server started on http://localhost:%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	return slog.New(slog.NewJSONHandler(os.Stderr, opts))
 }
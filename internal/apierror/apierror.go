@@ -0,0 +1,168 @@
+// Package apierror defines the body of every API error response and the
+// stable codes clients match on instead of the English messages.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Code identifies an error condition. Codes never change once published;
+// messages may be reworded at any time.
+type Code string
+
+const (
+	// The request is malformed: bad JSON, form or query syntax.
+	BadRequest Code = "request.invalid"
+	// The request is well-formed but some fields are missing or invalid;
+	// Error.Fields says which.
+	Validation       Code = "request.validation"
+	RouteNotFound    Code = "request.not_found"
+	MethodNotAllowed Code = "request.method_not_allowed"
+
+	// No usable credentials were sent.
+	Unauthorized Code = "auth.unauthorized"
+	// An access, refresh, reset or verification token is invalid or expired.
+	InvalidToken       Code = "auth.invalid_token"
+	SessionRevoked     Code = "auth.session_revoked"
+	InvalidCredentials Code = "auth.invalid_credentials"
+	EmailNotVerified   Code = "auth.email_not_verified"
+	EmailVerified      Code = "auth.email_already_verified"
+
+	UserExists         Code = "user.exists"
+	UserNotFound       Code = "user.not_found"
+	FolderNotFound     Code = "folder.not_found"
+	ImageNotFound      Code = "image.not_found"
+	UploadLinkNotFound Code = "upload_link.not_found"
+	// An upload link token is unknown, expired or revoked.
+	UploadLinkInvalid Code = "upload_link.invalid"
+
+	// Codes for a single file of an upload request.
+	UploadReadFailed      Code = "upload.read_failed"
+	UploadUnsupportedType Code = "upload.unsupported_type"
+	UploadTooLarge        Code = "upload.too_large"
+	UploadQuotaExceeded   Code = "upload.quota_exceeded"
+	UploadStorageFailed   Code = "upload.storage_failed"
+	UploadSaveFailed      Code = "upload.save_failed"
+	UploadCancelled       Code = "upload.cancelled"
+
+	Internal Code = "internal"
+)
+
+var statuses = map[Code]int{
+	BadRequest:            http.StatusBadRequest,
+	Validation:            http.StatusBadRequest,
+	RouteNotFound:         http.StatusNotFound,
+	MethodNotAllowed:      http.StatusMethodNotAllowed,
+	Unauthorized:          http.StatusUnauthorized,
+	InvalidToken:          http.StatusUnauthorized,
+	SessionRevoked:        http.StatusUnauthorized,
+	InvalidCredentials:    http.StatusUnauthorized,
+	EmailNotVerified:      http.StatusForbidden,
+	EmailVerified:         http.StatusConflict,
+	UserExists:            http.StatusConflict,
+	UserNotFound:          http.StatusNotFound,
+	FolderNotFound:        http.StatusNotFound,
+	ImageNotFound:         http.StatusNotFound,
+	UploadLinkNotFound:    http.StatusNotFound,
+	UploadLinkInvalid:     http.StatusForbidden,
+	UploadReadFailed:      http.StatusBadRequest,
+	UploadUnsupportedType: http.StatusUnsupportedMediaType,
+	UploadTooLarge:        http.StatusRequestEntityTooLarge,
+	UploadQuotaExceeded:   http.StatusConflict,
+	UploadStorageFailed:   http.StatusBadGateway,
+	UploadSaveFailed:      http.StatusInternalServerError,
+	UploadCancelled:       http.StatusBadRequest,
+	Internal:              http.StatusInternalServerError,
+}
+
+// Status is the HTTP status every response with this code uses.
+func (c Code) Status() int {
+	if s, ok := statuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Field-level validation codes.
+const (
+	FieldRequired = "required"
+	FieldInvalid  = "invalid"
+	FieldTooLong  = "too_long"
+)
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error is the JSON body of every error response. Message keeps the "error"
+// key that clients read before codes existed.
+type Error struct {
+	Code      Code         `json:"code"`
+	Message   string       `json:"error"`
+	Details   string       `json:"details,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Invalid reports a validation failure on the given fields.
+func Invalid(message string, fields ...FieldError) *Error {
+	return &Error{Code: Validation, Message: message, Fields: fields}
+}
+
+// Required reports a missing field.
+func Required(field string) FieldError {
+	return FieldError{Field: field, Code: FieldRequired}
+}
+
+// BadField reports a field whose value is not accepted.
+func BadField(field, message string) FieldError {
+	return FieldError{Field: field, Code: FieldInvalid, Message: message}
+}
+
+// TooLong reports a string field longer than max characters.
+func TooLong(field string, max int) FieldError {
+	return FieldError{Field: field, Code: FieldTooLong, Message: "must be at most " + strconv.Itoa(max) + " characters"}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// Status is the HTTP status for e's code.
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// RequestIDHeader carries the request ID. middleware.NewRequestLogger sets it
+// on the response before any handler runs; it is defined here because the
+// middleware package imports this one.
+const RequestIDHeader = "X-Request-ID"
+
+// Write sends e with the status for its code, tagged with the request ID.
+func Write(w http.ResponseWriter, e *Error) {
+	body := *e
+	if body.RequestID == "" {
+		body.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status())
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Respond writes a new error with code and message, plus optional details.
+func Respond(w http.ResponseWriter, code Code, message string, details ...string) {
+	e := New(code, message)
+	if len(details) > 0 {
+		e.Details = details[0]
+	}
+	Write(w, e)
+}
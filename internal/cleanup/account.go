@@ -0,0 +1,61 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// AccountDeletionReport summarises everything removed for a deleted user.
+type AccountDeletionReport struct {
+	UserID         string    `json:"userId"`
+	ImagesDeleted  int       `json:"imagesDeleted"`
+	FoldersDeleted int       `json:"foldersDeleted"`
+	QRCodeDeleted  bool      `json:"qrCodeDeleted"`
+	AssetsDeleted  int       `json:"assetsDeleted"`
+	AssetsQueued   int       `json:"assetsQueued"`
+	CompletedAt    time.Time `json:"completedAt"`
+}
+
+// PurgeUser deletes a user together with their images, folders and stored
+// assets. Database rows are removed in a single transaction; remote assets are
+// deleted afterwards, and any that fail are queued in pending_asset_deletions
+// so the sweeper finishes the erasure. The report is persisted in
+// account_deletions as a record of the request.
+func (c *Cleaner) PurgeUser(ctx context.Context, userID string) (AccountDeletionReport, error) {
+	report := AccountDeletionReport{UserID: userID}
+
+	res, err := c.users.Purge(ctx, userID)
+	if err != nil {
+		return report, err
+	}
+	report.ImagesDeleted = res.ImagesDeleted
+	report.FoldersDeleted = res.FoldersDeleted
+
+	// The rows are gone; remote deletion must not depend on the request staying open
+	assetCtx := context.WithoutCancel(ctx)
+	for _, key := range res.AssetKeys {
+		if err := c.DeleteAsset(assetCtx, key); err != nil {
+			report.AssetsQueued++
+			continue
+		}
+		report.AssetsDeleted++
+	}
+	if res.QRCodeKey != "" {
+		if err := c.DeleteAsset(assetCtx, res.QRCodeKey); err != nil {
+			report.AssetsQueued++
+		} else {
+			report.QRCodeDeleted = true
+		}
+	}
+	report.CompletedAt = time.Now().UTC()
+
+	if body, err := json.Marshal(report); err == nil {
+		if err := c.assets.RecordAccountDeletion(assetCtx, userID, body); err != nil {
+			log.Printf("cleanup: failed to record deletion report for %s: %v", userID, err)
+		}
+	}
+
+	return report, nil
+}
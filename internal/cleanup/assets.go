@@ -0,0 +1,94 @@
+package cleanup
+
+import (
+	"Backend/internal/repository"
+	"Backend/internal/storage"
+	"context"
+	"log"
+	"time"
+)
+
+// deleteAttempts is how many times a remote delete is tried inline before the
+// key is handed to the background sweeper.
+const deleteAttempts = 3
+
+// Cleaner removes stored assets and the accounts that own them.
+type Cleaner struct {
+	store  storage.Storage
+	users  repository.UserRepo
+	assets repository.AssetRepo
+}
+
+func New(store storage.Storage, repos repository.Repos) *Cleaner {
+	return &Cleaner{store: store, users: repos.Users, assets: repos.Assets}
+}
+
+// DeleteAsset removes key from the storage backend, retrying with exponential
+// backoff. If every attempt fails the key is queued in pending_asset_deletions
+// so RunSweeper can retry it later; the returned error is the last failure.
+func (c *Cleaner) DeleteAsset(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	err := c.deleteWithRetry(ctx, key)
+	if err == nil {
+		return nil
+	}
+
+	if qerr := c.assets.QueueDeletion(context.WithoutCancel(ctx), key, deleteAttempts, err.Error()); qerr != nil {
+		log.Printf("cleanup: failed to queue deletion of %s: %v", key, qerr)
+	}
+	return err
+}
+
+func (c *Cleaner) deleteWithRetry(ctx context.Context, key string) error {
+	backoff := 200 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= deleteAttempts; attempt++ {
+		if err = c.store.Delete(ctx, key); err == nil {
+			return nil
+		}
+		if attempt == deleteAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+// RunSweeper retries queued asset deletions every interval until ctx is cancelled.
+func (c *Cleaner) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.sweep(ctx)
+		}
+	}
+}
+
+func (c *Cleaner) sweep(ctx context.Context) {
+	keys, err := c.assets.PendingDeletions(ctx, 100)
+	if err != nil {
+		log.Printf("cleanup: failed to load pending deletions: %v", err)
+		return
+	}
+
+	for _, key := range keys {
+		if err := c.store.Delete(ctx, key); err != nil {
+			_ = c.assets.DeletionFailed(ctx, key, err.Error())
+			continue
+		}
+		_ = c.assets.DeletionDone(ctx, key)
+	}
+}
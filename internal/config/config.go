@@ -0,0 +1,247 @@
+// Package config loads the server's settings from defaults, an optional YAML
+// file, a .env file and the environment, in increasing order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"Backend/internal/db"
+	"Backend/internal/links"
+	"Backend/internal/mail"
+	"Backend/internal/storage"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting the server reads at startup.
+type Config struct {
+	// Env is "production" (the default) or "development".
+	Env  string `yaml:"env"`
+	Port string `yaml:"port"`
+	// MetricsAddr is the internal address /metrics is served on, kept apart
+	// from the public port; empty disables it.
+	MetricsAddr string `yaml:"metrics_addr"`
+	// LogLevel is debug, info (the default), warn or error.
+	LogLevel string `yaml:"log_level"`
+	// LogFormat is json (the default) or text.
+	LogFormat      string        `yaml:"log_format"`
+	DatabaseURL    string        `yaml:"database_url"`
+	DatabasePool   db.PoolConfig `yaml:"database_pool"`
+	MigrateOnStart bool          `yaml:"migrate_on_start"`
+	JWTSecret      string        `yaml:"jwt_secret"`
+	// FrontendURL is the base of every link sent to users.
+	FrontendURL string `yaml:"frontend_url"`
+	// EmailVerificationRequiredFor lists the features held back until the
+	// user's address is verified; see handlers.ParseVerificationPolicy.
+	EmailVerificationRequiredFor string `yaml:"email_verification_required_for"`
+
+	Server  ServerConfig   `yaml:"server"`
+	Uploads UploadConfig   `yaml:"uploads"`
+	Storage storage.Config `yaml:"storage"`
+	Mail    mail.Config    `yaml:"mail"`
+}
+
+// ServerConfig holds the HTTP server's timeouts. Read and write timeouts
+// bound whole requests, so they must leave room for the largest upload.
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests may take to finish
+	// after SIGTERM before the server closes their connections.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// UploadConfig bounds what guests may upload through upload links.
+type UploadConfig struct {
+	// MaxFileBytes is the largest single file accepted; 0 means no limit.
+	MaxFileBytes int64 `yaml:"max_file_bytes"`
+	// MaxRequestBytes caps the whole body of one upload request; 0 means no
+	// limit.
+	MaxRequestBytes int64 `yaml:"max_request_bytes"`
+	// Concurrency is how many files are processed at once across all
+	// requests. Each holds its decoded image in memory, and at most twice as
+	// many received images (up to 32 MiB each) wait for a turn. 0 leaves
+	// the choice to the upload handler.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Env:         "production",
+		Port:        "8080",
+		MetricsAddr: "127.0.0.1:9090",
+		LogLevel:    "info",
+		LogFormat:   "json",
+		DatabasePool: db.PoolConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Server: ServerConfig{
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       5 * time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Uploads: UploadConfig{
+			MaxFileBytes:    100 << 20,
+			MaxRequestBytes: 1 << 30,
+		},
+		Storage: storage.Config{
+			S3: storage.S3Options{UseSSL: true},
+		},
+	}
+}
+
+// Load reads .env (from the working directory or the repository root when
+// run from cmd/server), then the YAML file named by CONFIG_FILE if set, and
+// finally applies environment variables on top. It does not validate; call
+// Validate before using the result to start the server.
+func Load() (Config, error) {
+	if err := godotenv.Load(".env"); err != nil {
+		_ = godotenv.Load("../../.env")
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// DevMode reports whether the server runs in development mode, which exposes
+// secrets such as reset links in responses.
+func (c Config) DevMode() bool {
+	return c.Env == "development"
+}
+
+// SlogLevel parses LogLevel.
+func (c Config) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+// Validate reports every missing or malformed setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Env {
+	case "production", "development":
+	default:
+		add("APP_ENV must be production or development, got %q", c.Env)
+	}
+	if c.Port == "" {
+		add("PORT is required")
+	}
+	if c.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			add("METRICS_ADDR must be host:port, e.g. 127.0.0.1:9090")
+		} else if port == c.Port {
+			add("METRICS_ADDR must not use the public PORT")
+		}
+	}
+	if _, err := c.SlogLevel(); err != nil {
+		add("LOG_LEVEL: %v", err)
+	}
+	switch c.LogFormat {
+	case "json", "text":
+	default:
+		add("LOG_FORMAT must be json or text, got %q", c.LogFormat)
+	}
+	if c.DatabaseURL == "" {
+		add("DATABASE_URL is required")
+	}
+	pool := c.DatabasePool
+	if pool.MaxOpenConns < 0 || pool.MaxIdleConns < 0 || pool.ConnMaxLifetime < 0 || pool.ConnMaxIdleTime < 0 {
+		add("database pool settings must not be negative")
+	}
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", pool.MaxIdleConns, pool.MaxOpenConns)
+	}
+	srv := c.Server
+	if srv.ReadHeaderTimeout <= 0 || srv.ReadTimeout <= 0 || srv.WriteTimeout <= 0 || srv.IdleTimeout <= 0 || srv.ShutdownTimeout <= 0 {
+		add("server timeouts must be positive")
+	}
+	if c.Uploads.MaxFileBytes < 0 {
+		add("UPLOAD_MAX_FILE_BYTES must not be negative")
+	}
+	if c.Uploads.MaxRequestBytes < 0 {
+		add("UPLOAD_MAX_REQUEST_BYTES must not be negative")
+	}
+	if c.Uploads.Concurrency < 0 {
+		add("UPLOAD_CONCURRENCY must not be negative")
+	}
+	if strings.TrimSpace(c.JWTSecret) == "" {
+		add("JWT_SECRET is required")
+	}
+	if _, err := links.New(c.FrontendURL); err != nil {
+		add("FRONTEND_URL: %v", err)
+	}
+
+	switch backend := strings.ToLower(strings.TrimSpace(c.Storage.Backend)); backend {
+	case "", "cloudinary":
+		cld := c.Storage.Cloudinary
+		if cld.CloudName == "" || cld.APIKey == "" || cld.APISecret == "" {
+			add("CLOUDINARY_CLOUD_NAME, CLOUDINARY_API_KEY and CLOUDINARY_API_SECRET are required for the cloudinary storage backend")
+		}
+	case "local":
+	case "s3", "minio":
+		s3 := c.Storage.S3
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			add("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 storage backend")
+		}
+		if s3.PublicURL == "" {
+			add("S3_PUBLIC_URL is required for the s3 storage backend")
+		}
+	default:
+		add("unknown STORAGE_BACKEND %q", backend)
+	}
+
+	switch transport := strings.ToLower(strings.TrimSpace(c.Mail.Transport)); transport {
+	case "", "smtp":
+		if c.Mail.Sender == "" || c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port == "" {
+			add("EMAIL_SENDER, SMTP_HOST and SMTP_PORT are required for the smtp mail transport")
+		}
+	case "file", "log":
+	default:
+		add("unknown MAIL_TRANSPORT %q", transport)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns defaults completed with the settings that have none.
+func validConfig() Config {
+	c := Default()
+	c.DatabaseURL = "postgres://localhost/photos"
+	c.JWTSecret = "secret"
+	c.FrontendURL = "https://app.example.com"
+	c.Storage.Backend = "local"
+	c.Mail.Transport = "log"
+	return c
+}
+
+func TestValidateAcceptsCompleteConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultMetricsAddrIsLoopback(t *testing.T) {
+	if addr := Default().MetricsAddr; !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("default MetricsAddr %q is not bound to loopback", addr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"unknown env", func(c *Config) { c.Env = "staging" }, "APP_ENV"},
+		{"no port", func(c *Config) { c.Port = "" }, "PORT is required"},
+		{"metrics addr without port", func(c *Config) { c.MetricsAddr = "localhost" }, "METRICS_ADDR must be host:port"},
+		{"metrics on public port", func(c *Config) { c.MetricsAddr = ":8080" }, "METRICS_ADDR must not use the public PORT"},
+		{"log level", func(c *Config) { c.LogLevel = "loud" }, "LOG_LEVEL"},
+		{"log format", func(c *Config) { c.LogFormat = "xml" }, "LOG_FORMAT"},
+		{"no database", func(c *Config) { c.DatabaseURL = "" }, "DATABASE_URL is required"},
+		{"negative pool", func(c *Config) { c.DatabasePool.MaxOpenConns = -1 }, "must not be negative"},
+		{"idle above open", func(c *Config) { c.DatabasePool.MaxIdleConns = 50 }, "DB_MAX_IDLE_CONNS"},
+		{"zero timeout", func(c *Config) { c.Server.WriteTimeout = 0 }, "server timeouts must be positive"},
+		{"negative file limit", func(c *Config) { c.Uploads.MaxFileBytes = -1 }, "UPLOAD_MAX_FILE_BYTES"},
+		{"negative request limit", func(c *Config) { c.Uploads.MaxRequestBytes = -1 }, "UPLOAD_MAX_REQUEST_BYTES"},
+		{"negative concurrency", func(c *Config) { c.Uploads.Concurrency = -1 }, "UPLOAD_CONCURRENCY"},
+		{"blank JWT secret", func(c *Config) { c.JWTSecret = "  " }, "JWT_SECRET is required"},
+		{"no frontend", func(c *Config) { c.FrontendURL = "" }, "FRONTEND_URL"},
+		{"cloudinary without credentials", func(c *Config) { c.Storage.Backend = "" }, "CLOUDINARY_CLOUD_NAME"},
+		{"s3 without settings", func(c *Config) { c.Storage.Backend = "s3" }, "S3_ENDPOINT"},
+		{"s3 without public url", func(c *Config) {
+			c.Storage.Backend = "minio"
+			c.Storage.S3.Endpoint, c.Storage.S3.Bucket = "minio:9000", "photos"
+			c.Storage.S3.AccessKey, c.Storage.S3.SecretKey = "key", "secret"
+		}, "S3_PUBLIC_URL is required"},
+		{"unknown storage", func(c *Config) { c.Storage.Backend = "ftp" }, "unknown STORAGE_BACKEND"},
+		{"smtp without host", func(c *Config) { c.Mail.Transport = "smtp" }, "SMTP_HOST"},
+		{"unknown transport", func(c *Config) { c.Mail.Transport = "fax" }, "unknown MAIL_TRANSPORT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := validConfig()
+	c.Port = ""
+	c.JWTSecret = ""
+	c.DatabaseURL = ""
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, want := range []string{"PORT", "JWT_SECRET", "DATABASE_URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("PORT", "3000")
+	t.Setenv("MIGRATE_ON_START", "true")
+	t.Setenv("DB_MAX_OPEN_CONNS", "7")
+	t.Setenv("SHUTDOWN_TIMEOUT", "5s")
+	t.Setenv("UPLOAD_MAX_FILE_BYTES", "1048576")
+	t.Setenv("FRONTEND_API", "https://old.example.com")
+	t.Setenv("FRONTEND_URL", "https://new.example.com")
+	t.Setenv("S3_USE_SSL", "false")
+	// Empty variables leave the value alone
+	t.Setenv("LOG_LEVEL", "")
+
+	c := Default()
+	if err := c.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.DevMode() || c.Port != "3000" || !c.MigrateOnStart {
+		t.Errorf("Env %q, Port %q, MigrateOnStart %v", c.Env, c.Port, c.MigrateOnStart)
+	}
+	if c.DatabasePool.MaxOpenConns != 7 || c.Server.ShutdownTimeout != 5*time.Second || c.Uploads.MaxFileBytes != 1<<20 {
+		t.Errorf("pool %d, shutdown %v, max file %d", c.DatabasePool.MaxOpenConns, c.Server.ShutdownTimeout, c.Uploads.MaxFileBytes)
+	}
+	if c.FrontendURL != "https://new.example.com" {
+		t.Errorf("FrontendURL %q, want FRONTEND_URL to win over FRONTEND_API", c.FrontendURL)
+	}
+	if c.Storage.S3.UseSSL {
+		t.Error("S3_USE_SSL=false did not apply")
+	}
+	if c.LogLevel != "info" {
+		t.Errorf("LogLevel %q, want the default", c.LogLevel)
+	}
+}
+
+func TestApplyEnvReportsEveryMalformedValue(t *testing.T) {
+	t.Setenv("MIGRATE_ON_START", "sometimes")
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	t.Setenv("HTTP_READ_TIMEOUT", "10")
+	t.Setenv("UPLOAD_MAX_REQUEST_BYTES", "1GB")
+
+	c := Default()
+	err := c.applyEnv()
+	if err == nil {
+		t.Fatal("applyEnv succeeded")
+	}
+	for _, want := range []string{"MIGRATE_ON_START", "DB_MAX_OPEN_CONNS", "HTTP_READ_TIMEOUT", "UPLOAD_MAX_REQUEST_BYTES"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeFile(t, `
+port: "9000"
+metrics_addr: "127.0.0.1:9100"
+server:
+  read_timeout: 90s
+uploads:
+  concurrency: 8
+storage:
+  backend: local
+  local:
+    dir: /var/photos
+mail:
+  transport: file
+`)
+
+	c := Default()
+	if err := c.loadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != "9000" || c.MetricsAddr != "127.0.0.1:9100" || c.Server.ReadTimeout != 90*time.Second || c.Uploads.Concurrency != 8 {
+		t.Errorf("port %q, metrics %q, read timeout %v, concurrency %d", c.Port, c.MetricsAddr, c.Server.ReadTimeout, c.Uploads.Concurrency)
+	}
+	if c.Storage.Local.Dir != "/var/photos" || c.Mail.Transport != "file" {
+		t.Errorf("storage dir %q, mail transport %q", c.Storage.Local.Dir, c.Mail.Transport)
+	}
+	// Settings the file leaves out keep their defaults
+	if c.Server.WriteTimeout != Default().Server.WriteTimeout || c.LogFormat != "json" {
+		t.Errorf("write timeout %v, log format %q", c.Server.WriteTimeout, c.LogFormat)
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "prot: \"9000\"\n")
+	c := Default()
+	if err := c.loadFile(path); err == nil {
+		t.Error("loadFile accepted a misspelt key")
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	c := Default()
+	if err := c.loadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("loadFile succeeded on a missing file")
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	t.Setenv("PORT", "4000")
+	c := Default()
+	if err := c.loadFile(writeFile(t, "port: \"9000\"\nlog_format: text\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != "4000" || c.LogFormat != "text" {
+		t.Errorf("port %q, log format %q; want the environment's port and the file's format", c.Port, c.LogFormat)
+	}
+}
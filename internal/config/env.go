@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// applyEnv overrides c with every variable that is set and non-empty.
+func (c *Config) applyEnv() error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be true or false, got %q", name, v))
+			return
+		}
+		*dst = b
+	}
+	integer := func(name string, dst *int) {
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be an integer, got %q", name, v))
+			return
+		}
+		*dst = n
+	}
+	integer64 := func(name string, dst *int64) {
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be an integer, got %q", name, v))
+			return
+		}
+		*dst = n
+	}
+	duration := func(name string, dst *time.Duration) {
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a duration such as 30s, got %q", name, v))
+			return
+		}
+		*dst = d
+	}
+
+	str("APP_ENV", &c.Env)
+	str("PORT", &c.Port)
+	str("METRICS_ADDR", &c.MetricsAddr)
+	str("LOG_LEVEL", &c.LogLevel)
+	str("LOG_FORMAT", &c.LogFormat)
+	str("DATABASE_URL", &c.DatabaseURL)
+	integer("DB_MAX_OPEN_CONNS", &c.DatabasePool.MaxOpenConns)
+	integer("DB_MAX_IDLE_CONNS", &c.DatabasePool.MaxIdleConns)
+	duration("DB_CONN_MAX_LIFETIME", &c.DatabasePool.ConnMaxLifetime)
+	duration("DB_CONN_MAX_IDLE_TIME", &c.DatabasePool.ConnMaxIdleTime)
+	boolean("MIGRATE_ON_START", &c.MigrateOnStart)
+	str("JWT_SECRET", &c.JWTSecret)
+	// FRONTEND_API is the older name of FRONTEND_URL
+	str("FRONTEND_API", &c.FrontendURL)
+	str("FRONTEND_URL", &c.FrontendURL)
+	str("EMAIL_VERIFICATION_REQUIRED_FOR", &c.EmailVerificationRequiredFor)
+
+	duration("HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	duration("HTTP_READ_TIMEOUT", &c.Server.ReadTimeout)
+	duration("HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	duration("HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	integer64("UPLOAD_MAX_FILE_BYTES", &c.Uploads.MaxFileBytes)
+	integer64("UPLOAD_MAX_REQUEST_BYTES", &c.Uploads.MaxRequestBytes)
+	integer("UPLOAD_CONCURRENCY", &c.Uploads.Concurrency)
+
+	str("STORAGE_BACKEND", &c.Storage.Backend)
+	str("CLOUDINARY_CLOUD_NAME", &c.Storage.Cloudinary.CloudName)
+	str("CLOUDINARY_API_KEY", &c.Storage.Cloudinary.APIKey)
+	str("CLOUDINARY_API_SECRET", &c.Storage.Cloudinary.APISecret)
+	str("LOCAL_STORAGE_DIR", &c.Storage.Local.Dir)
+	str("LOCAL_STORAGE_BASE_URL", &c.Storage.Local.BaseURL)
+	str("S3_ENDPOINT", &c.Storage.S3.Endpoint)
+	str("S3_REGION", &c.Storage.S3.Region)
+	str("S3_BUCKET", &c.Storage.S3.Bucket)
+	str("S3_ACCESS_KEY", &c.Storage.S3.AccessKey)
+	str("S3_SECRET_KEY", &c.Storage.S3.SecretKey)
+	boolean("S3_USE_SSL", &c.Storage.S3.UseSSL)
+	str("S3_PUBLIC_URL", &c.Storage.S3.PublicURL)
+
+	str("EMAIL_SENDER", &c.Mail.Sender)
+	str("MAIL_TRANSPORT", &c.Mail.Transport)
+	str("MAIL_OUTBOX_DIR", &c.Mail.OutboxDir)
+	str("SMTP_HOST", &c.Mail.SMTP.Host)
+	str("SMTP_PORT", &c.Mail.SMTP.Port)
+	str("SMTP_USERNAME", &c.Mail.SMTP.Username)
+	str("SMTP_PASSWORD", &c.Mail.SMTP.Password)
+
+	return errors.Join(errs...)
+}
@@ -3,18 +3,25 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func ConnectDB() error {
-	// Load DB URL from environment variable
-	dbURL := os.Getenv("DATABASE_URL")
+// PoolConfig bounds the connection pool. Zero values keep database/sql's
+// defaults (unlimited open connections, 2 idle, no lifetime).
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+func ConnectDB(dbURL string, pool PoolConfig) error {
 	if dbURL == "" {
-		return fmt.Errorf("DATABASE_URL not set in environment")
+		return fmt.Errorf("database URL is not set")
 	}
 
 	// Open the database connection
@@ -23,6 +30,12 @@ func ConnectDB() error {
 	if err != nil {
 		return fmt.Errorf("failed to open DB connection: %v", err)
 	}
+	DB.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		DB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	DB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Verify the connection
 	if err := DB.Ping(); err != nil {
@@ -33,9 +46,8 @@ func ConnectDB() error {
 	return nil
 }
 
-
 func CloseDB() {
 	if DB != nil {
 		DB.Close()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d_%s: want version %d; versions must be consecutive", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has an empty up or down script", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		if m.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("migration %d_%s: checksum does not match its up script", m.Version, m.Name)
+		}
+	}
+
+	again, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(migrations, again) {
+		t.Error("LoadMigrations is not deterministic")
+	}
+}
+
+func TestMigrateUpAppliesPendingInOrder(t *testing.T) {
+	migrations := loadMigrations(t)
+	fake, db := openFake(t)
+	fake.markApplied(migrations[:2]...)
+
+	n, err := MigrateUp(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(migrations)-2 {
+		t.Errorf("applied %d migrations, want %d", n, len(migrations)-2)
+	}
+	var want []string
+	for _, m := range migrations[2:] {
+		want = append(want, m.Up)
+	}
+	if !reflect.DeepEqual(fake.scripts, want) {
+		t.Errorf("ran %d scripts, want the up scripts of migrations 3 to %d", len(fake.scripts), len(migrations))
+	}
+	if len(fake.applied) != len(migrations) {
+		t.Errorf("%d migrations recorded, want %d", len(fake.applied), len(migrations))
+	}
+	if fake.locked != 0 {
+		t.Error("migration lock was not released")
+	}
+
+	n, err = MigrateUp(context.Background(), db)
+	if err != nil || n != 0 {
+		t.Errorf("second MigrateUp = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestMigrateDownRevertsNewestFirst(t *testing.T) {
+	migrations := loadMigrations(t)
+	fake, db := openFake(t)
+	fake.markApplied(migrations...)
+
+	n, err := MigrateDown(context.Background(), db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("reverted %d migrations, want 2", n)
+	}
+	last := len(migrations) - 1
+	want := []string{migrations[last].Down, migrations[last-1].Down}
+	if !reflect.DeepEqual(fake.scripts, want) {
+		t.Error("did not run the down scripts of the two newest migrations, newest first")
+	}
+	if _, ok := fake.applied[migrations[last].Version]; ok {
+		t.Error("reverted migration is still recorded")
+	}
+	if _, ok := fake.applied[migrations[last-2].Version]; !ok {
+		t.Error("older migration was reverted")
+	}
+}
+
+func TestMigrateUpStopsAtFailingMigration(t *testing.T) {
+	migrations := loadMigrations(t)
+	fake, db := openFake(t)
+	fake.failScript = migrations[1].Up
+
+	n, err := MigrateUp(context.Background(), db)
+	if err == nil {
+		t.Fatal("MigrateUp succeeded")
+	}
+	if n != 1 {
+		t.Errorf("applied %d migrations, want 1", n)
+	}
+	if _, ok := fake.applied[migrations[1].Version]; ok {
+		t.Error("failed migration was recorded")
+	}
+	if len(fake.applied) != 1 {
+		t.Errorf("%d migrations recorded, want 1", len(fake.applied))
+	}
+}
+
+func TestMigrateRefusesUnknownOrModifiedMigrations(t *testing.T) {
+	migrations := loadMigrations(t)
+
+	t.Run("modified", func(t *testing.T) {
+		fake, db := openFake(t)
+		fake.applied[migrations[0].Version] = appliedMigration{Checksum: "edited", AppliedAt: time.Now()}
+
+		if _, err := MigrateUp(context.Background(), db); err == nil || !strings.Contains(err.Error(), "checksum") {
+			t.Errorf("err = %v, want a checksum mismatch", err)
+		}
+		if len(fake.scripts) != 0 {
+			t.Error("ran migrations despite the mismatch")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		fake, db := openFake(t)
+		fake.applied[9999] = appliedMigration{Checksum: "x", AppliedAt: time.Now()}
+
+		if _, err := MigrateUp(context.Background(), db); err == nil {
+			t.Error("MigrateUp accepted a migration this build does not know")
+		}
+		if len(fake.scripts) != 0 {
+			t.Error("ran migrations despite the unknown version")
+		}
+	})
+}
+
+func TestMigrationStatuses(t *testing.T) {
+	migrations := loadMigrations(t)
+	fake, db := openFake(t)
+	fake.markApplied(migrations[0])
+
+	statuses, err := MigrationStatuses(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(migrations))
+	}
+	if statuses[0].AppliedAt == nil {
+		t.Error("applied migration has no AppliedAt")
+	}
+	for _, s := range statuses[1:] {
+		if s.AppliedAt != nil {
+			t.Errorf("pending migration %d has AppliedAt", s.Version)
+		}
+	}
+}
+
+func loadMigrations(t *testing.T) []Migration {
+	t.Helper()
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) < 3 {
+		t.Fatal("tests need at least three migrations")
+	}
+	return migrations
+}
+
+// fakeDB is a database/sql driver that understands just the statements the
+// migrator sends: it records migration scripts and keeps schema_migrations in
+// memory, committing its rows only with the transaction.
+type fakeDB struct {
+	mu         sync.Mutex
+	applied    map[int]appliedMigration
+	scripts    []string
+	failScript string
+	locked     int
+}
+
+func openFake(t *testing.T) (*fakeDB, *sql.DB) {
+	t.Helper()
+	fake := &fakeDB{applied: map[int]appliedMigration{}}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return fake, db
+}
+
+func (f *fakeDB) markApplied(migrations ...Migration) {
+	for _, m := range migrations {
+		f.applied[m.Version] = appliedMigration{Checksum: m.Checksum, AppliedAt: time.Now()}
+	}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+	// pending holds schema_migrations changes until the transaction commits
+	pending []func()
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver: prepared statements are not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, apply := range c.pending {
+		apply()
+	}
+	c.pending = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_advisory_lock"):
+		f.locked++
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock"):
+		f.locked--
+	case strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		version := int(args[0].Value.(int64))
+		checksum := args[2].Value.(string)
+		c.pending = append(c.pending, func() {
+			f.applied[version] = appliedMigration{Checksum: checksum, AppliedAt: time.Now()}
+		})
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		version := int(args[0].Value.(int64))
+		c.pending = append(c.pending, func() { delete(f.applied, version) })
+	default:
+		if query == f.failScript {
+			return nil, errors.New("syntax error")
+		}
+		f.scripts = append(f.scripts, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, "SELECT version, checksum, applied_at FROM schema_migrations") {
+		return nil, errors.New("fake driver: unexpected query")
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	rows := &fakeRows{}
+	for version, a := range c.db.applied {
+		rows.values = append(rows.values, []driver.Value{int64(version), a.Checksum, a.AppliedAt})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "checksum", "applied_at"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,7 @@
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_user_id_fkey;
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_user_id_fkey;
+
+ALTER TABLE folders ALTER COLUMN user_id TYPE VARCHAR(255) USING user_id::text;
+ALTER TABLE images ALTER COLUMN user_id TYPE VARCHAR(255) USING user_id::text;
+
+DROP TABLE IF EXISTS pending_asset_deletions;
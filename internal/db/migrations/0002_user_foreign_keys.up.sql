@@ -0,0 +1,40 @@
+-- images and folders were created with VARCHAR user ids and no foreign keys;
+-- convert them to UUIDs referencing users so rows cannot outlive their owner.
+-- Rows whose owner no longer exists are removed first, or the constraint fails;
+-- their stored files are queued for deletion rather than leaked.
+
+-- Remote assets whose deletion failed or is still due; the sweeper retries them
+CREATE TABLE IF NOT EXISTS pending_asset_deletions (
+    storage_key TEXT PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Queue the files of orphaned images so they are not left in storage forever.
+-- These rows predate storage keys and only have their Cloudinary URL, whose
+-- path after upload/ and the optional version is the public id plus extension.
+INSERT INTO pending_asset_deletions (storage_key, last_error)
+SELECT DISTINCT
+    regexp_replace(
+        regexp_replace(image_url, '^https?://res\.cloudinary\.com/[^/]+/[^/]+/upload/(v[0-9]+/)?', ''),
+        '\.[^./]*$', ''),
+    'owner deleted before foreign keys were added'
+FROM images
+WHERE lower(user_id) NOT IN (SELECT id::text FROM users)
+  AND image_url ~ '^https?://res\.cloudinary\.com/[^/]+/[^/]+/upload/'
+ON CONFLICT (storage_key) DO NOTHING;
+
+DELETE FROM images WHERE lower(user_id) NOT IN (SELECT id::text FROM users);
+DELETE FROM folders WHERE lower(user_id) NOT IN (SELECT id::text FROM users);
+
+ALTER TABLE images ALTER COLUMN user_id TYPE UUID USING user_id::uuid;
+ALTER TABLE folders ALTER COLUMN user_id TYPE UUID USING user_id::uuid;
+
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_user_id_fkey;
+ALTER TABLE images ADD CONSTRAINT images_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_user_id_fkey;
+ALTER TABLE folders ADD CONSTRAINT folders_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
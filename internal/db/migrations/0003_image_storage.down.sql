@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS account_deletions;
+ALTER TABLE images DROP COLUMN IF EXISTS deletion_token_hash;
+ALTER TABLE images DROP COLUMN IF EXISTS folder_id;
+ALTER TABLE users DROP COLUMN IF EXISTS qr_code_key;
+ALTER TABLE images DROP COLUMN IF EXISTS storage_key;
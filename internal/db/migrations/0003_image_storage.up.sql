@@ -0,0 +1,21 @@
+-- Storage provider key (e.g. Cloudinary public_id) so assets can be deleted
+ALTER TABLE images ADD COLUMN IF NOT EXISTS storage_key TEXT;
+
+-- Storage key of the user's QR code asset so it can be removed with the account
+ALTER TABLE users ADD COLUMN IF NOT EXISTS qr_code_key TEXT;
+
+-- Folder an image is filed under; images become unfiled when their folder is deleted
+ALTER TABLE images ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_images_folder_id ON images(folder_id);
+
+-- Hash of the per-upload token that lets a guest delete their own upload
+ALTER TABLE images ADD COLUMN IF NOT EXISTS deletion_token_hash TEXT;
+
+-- Record of every account erasure and what it removed; deliberately not a
+-- foreign key, since the user row is gone by the time it is written
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    report JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE images DROP COLUMN IF EXISTS upload_link_id;
+DROP TABLE IF EXISTS upload_links;
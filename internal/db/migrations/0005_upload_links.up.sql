@@ -0,0 +1,20 @@
+-- Guest upload links; the token itself is never stored, only its SHA-256 hash
+CREATE TABLE IF NOT EXISTS upload_links (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    max_files BIGINT,
+    max_bytes BIGINT,
+    files_uploaded BIGINT NOT NULL DEFAULT 0,
+    bytes_uploaded BIGINT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_upload_links_user_id ON upload_links(user_id);
+
+-- Upload link an image arrived through
+ALTER TABLE images ADD COLUMN IF NOT EXISTS upload_link_id UUID REFERENCES upload_links(id) ON DELETE SET NULL;
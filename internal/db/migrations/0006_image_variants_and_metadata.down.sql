@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_folders_user_name_id;
+DROP INDEX IF EXISTS idx_folders_user_created_id;
+DROP INDEX IF EXISTS idx_images_user_captured_id;
+DROP INDEX IF EXISTS idx_images_user_size_id;
+DROP INDEX IF EXISTS idx_images_user_created_id;
+DROP INDEX IF EXISTS idx_images_storage_key;
+DROP TABLE IF EXISTS image_variants;
+ALTER TABLE images DROP COLUMN IF EXISTS gps_longitude;
+ALTER TABLE images DROP COLUMN IF EXISTS gps_latitude;
+ALTER TABLE images DROP COLUMN IF EXISTS orientation;
+ALTER TABLE images DROP COLUMN IF EXISTS camera_model;
+ALTER TABLE images DROP COLUMN IF EXISTS camera_make;
+ALTER TABLE images DROP COLUMN IF EXISTS captured_at;
+ALTER TABLE images DROP COLUMN IF EXISTS size_bytes;
+ALTER TABLE images DROP COLUMN IF EXISTS height;
+ALTER TABLE images DROP COLUMN IF EXISTS width;
@@ -0,0 +1,35 @@
+-- Original dimensions (null for non-image uploads) and size
+ALTER TABLE images ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS height INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS size_bytes BIGINT;
+
+-- EXIF metadata; captured_at is the camera's wall-clock time, which rarely has a time zone
+ALTER TABLE images ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS camera_make VARCHAR(255);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS camera_model VARCHAR(255);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS orientation SMALLINT;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS gps_latitude DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS gps_longitude DOUBLE PRECISION;
+
+-- Resized renditions of an image; the original lives on the images row
+CREATE TABLE IF NOT EXISTS image_variants (
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    variant VARCHAR(32) NOT NULL,
+    storage_key TEXT NOT NULL,
+    url TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (image_id, variant)
+);
+CREATE INDEX IF NOT EXISTS idx_image_variants_storage_key ON image_variants(storage_key);
+CREATE INDEX IF NOT EXISTS idx_images_storage_key ON images(storage_key);
+
+-- Keyset pagination indexes for GET /images and GET /folders
+CREATE INDEX IF NOT EXISTS idx_images_user_created_id ON images(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_images_user_size_id ON images(user_id, (COALESCE(size_bytes, 0)), id);
+-- Matches the captured sort and the timeline, which fall back to the upload time
+CREATE INDEX IF NOT EXISTS idx_images_user_captured_id ON images(user_id, (COALESCE(captured_at, created_at AT TIME ZONE 'UTC')), id);
+CREATE INDEX IF NOT EXISTS idx_folders_user_created_id ON folders(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_folders_user_name_id ON folders(user_id, name, id);
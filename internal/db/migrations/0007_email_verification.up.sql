@@ -0,0 +1,5 @@
+-- Accounts must prove they own their email address before some features unlock
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts created before verification existed keep working as they did
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
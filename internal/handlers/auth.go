@@ -1,179 +1,279 @@
-package handlers
-
-import (
-	"Backend/internal/db"
-	"Backend/internal/utils"
-	"database/sql"
-	"encoding/json"
-	"net/http"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-)
-
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
-type SignUpReponse struct {
-	Message string `json:"message"`
-	Email   string `json:"email"`
-}
-
-type LoginResponse struct {
-	Token   string `json:"token"`
-	Message string `json:"message"`
-	Role    string `json:"role"`
-	UserID  string `json:"userID"`
-}
-
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
-}
-
-func respondWithError(w http.ResponseWriter, statusCode int, message string, details ...string) {
-	resp := ErrorResponse{
-		Error: message,
-	}
-	if len(details) > 0 {
-		resp.Details = details[0]
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(resp)
-}
-
-func SignUpHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Failed to parse multipart form")
-		return
-	}
-
-	firstName := r.FormValue("firstName")
-	lastName := r.FormValue("lastName")
-	email := strings.ToLower(r.FormValue("email"))
-	password := r.FormValue("password")
-	id := uuid.New()
-
-	if email == "" || password == "" {
-		respondWithError(w, http.StatusBadRequest, "Email and password are required")
-		return
-	}
-
-	// Check if user already exists
-	var existingID string
-	err = db.DB.QueryRow(`SELECT id FROM users WHERE email = $1`, email).Scan(&existingID)
-	if err == nil {
-		// User found
-		respondWithError(w, http.StatusConflict, "User already exists with this email")
-		return
-	} else if err != sql.ErrNoRows {
-		// Some other DB error
-		respondWithError(w, http.StatusInternalServerError, "Database error while checking user")
-		return
-	}
-
-	// Generate QR code
-	QRCodeLink, err := utils.GenerateQRCode(id.String())
-	if err != nil || QRCodeLink == "" {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate QR code link")
-		return
-	}
-
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
-		return
-	}
-
-	// Insert into database
-	_, err = db.DB.Exec(`
-		INSERT INTO users (id, first_name, last_name, email, password, qr_code_link)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, id, firstName, lastName, email, string(hashedPassword), QRCodeLink)
-
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to save user in database")
-		return
-	}
-
-	// Success response
-	response := SignUpReponse{
-		Message: "Signed up successfully",
-		Email:   email,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
-}
-
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Failed to parse form")
-		return
-	}
-
-	email := strings.ToLower(r.FormValue("email"))
-	password := r.FormValue("password")
-
-	if email == "" || password == "" {
-		respondWithError(w, http.StatusBadRequest, "Email and password are required")
-		return
-	}
-
-	// Query user from DB
-	var id, hashedPassword, role string
-	err = db.DB.QueryRow(`SELECT id, password, role FROM users WHERE email = $1`, email).Scan(&id, &hashedPassword, &role)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid email or password")
-		return
-	}
-	role = strings.Trim(role, `"`)
-	// Compare hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid email or password")
-		return
-	}
-
-	// Create JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": id,
-		"email":   email,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
-		return
-	}
-	response := LoginResponse{
-		Token:   tokenString,
-		Message: "User LoggedIn successfully",
-		Role:    role,
-		UserID:  id,
-	}
-	// Return token
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
-}
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"Backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type SignUpReponse struct {
+	Message string `json:"message"`
+	Email   string `json:"email"`
+}
+
+type LoginResponse struct {
+	Token         string `json:"token"`
+	RefreshToken  string `json:"refreshToken"`
+	ExpiresIn     int    `json:"expiresIn"`
+	Message       string `json:"message"`
+	Role          string `json:"role"`
+	UserID        string `json:"userID"`
+	EmailVerified bool   `json:"emailVerified"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+func (h *Handler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, apierror.MethodNotAllowed, "Only POST allowed")
+		return
+	}
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		respondWithError(w, apierror.BadRequest, "Failed to parse multipart form")
+		return
+	}
+
+	firstName := r.FormValue("firstName")
+	lastName := r.FormValue("lastName")
+	email := strings.ToLower(r.FormValue("email"))
+	password := r.FormValue("password")
+	id := uuid.New()
+
+	if email == "" || password == "" {
+		respondWithValidation(w, "Email and password are required", missing("email", email, "password", password)...)
+		return
+	}
+
+	// Check if user already exists
+	_, err = h.repos.Users.GetByEmail(r.Context(), email)
+	if err == nil {
+		// User found
+		respondWithError(w, apierror.UserExists, "User already exists with this email")
+		return
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		// Some other DB error
+		respondWithError(w, apierror.Internal, "Database error while checking user")
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to hash password")
+		return
+	}
+
+	// Insert into database
+	user := repository.User{
+		ID:           id.String(),
+		FirstName:    firstName,
+		LastName:     lastName,
+		Email:        email,
+		PasswordHash: string(hashedPassword),
+	}
+	err = h.repos.Users.Create(r.Context(), user)
+	if errors.Is(err, repository.ErrConflict) {
+		respondWithError(w, apierror.UserExists, "User already exists with this email")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to save user in database")
+		return
+	}
+
+	// Every account gets a default upload link, encoded in its QR code,
+	// unless the policy holds it back until the email is verified
+	if !h.verification.QRCode {
+		if err := h.provisionQRCode(r.Context(), user.ID); err != nil {
+			middleware.Logger(r.Context()).Error("provision QR code", "user_id", user.ID, "error", err)
+			_ = h.repos.Users.Delete(r.Context(), user.ID)
+			respondWithError(w, apierror.Internal, "Failed to generate QR code link")
+			return
+		}
+	}
+
+	// The account works without it; the user can ask for another link
+	_ = h.sendVerificationEmail(r, user)
+
+	// Success response
+	response := SignUpReponse{
+		Message: "Signed up successfully",
+		Email:   email,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, apierror.MethodNotAllowed, "Only POST allowed")
+		return
+	}
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		respondWithError(w, apierror.BadRequest, "Failed to parse form")
+		return
+	}
+
+	email := strings.ToLower(r.FormValue("email"))
+	password := r.FormValue("password")
+
+	if email == "" || password == "" {
+		respondWithValidation(w, "Email and password are required", missing("email", email, "password", password)...)
+		return
+	}
+
+	// Query user from DB
+	user, err := h.repos.Users.GetByEmail(r.Context(), email)
+	if err != nil {
+		respondWithError(w, apierror.InvalidCredentials, "Invalid email or password")
+		return
+	}
+	id := user.ID
+	role := strings.Trim(user.Role, `"`)
+	// Compare hashed password
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		respondWithError(w, apierror.InvalidCredentials, "Invalid email or password")
+		return
+	}
+
+	// Open a session and issue tokens bound to it
+	sessionID, refreshToken, err := h.createSession(r.Context(), id)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to create session")
+		return
+	}
+
+	tokenString, err := utils.GenerateAccessToken(h.jwtSecret, id, email, sessionID)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to generate token")
+		return
+	}
+	response := LoginResponse{
+		Token:         tokenString,
+		RefreshToken:  refreshToken,
+		ExpiresIn:     int(utils.AccessTokenTTL.Seconds()),
+		Message:       "User LoggedIn successfully",
+		Role:          role,
+		UserID:        id,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
+	// Return token
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /refresh
+func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondWithValidation(w, "Refresh token is required", apierror.Required("refreshToken"))
+		return
+	}
+
+	// The presented token stops working immediately; presenting it again
+	// revokes the whole session, since that means it was copied.
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to refresh session")
+		return
+	}
+	userID, email, sessionID, err := h.repos.Sessions.Rotate(r.Context(), utils.HashToken(req.RefreshToken),
+		utils.HashToken(refreshToken), time.Now().Add(utils.RefreshTokenTTL))
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.InvalidToken, "Invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to refresh session")
+		return
+	}
+
+	tokenString, err := utils.GenerateAccessToken(h.jwtSecret, userID, email, sessionID)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to generate token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RefreshResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	})
+}
+
+// POST /logout
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	if userID == "" || sessionID == "" {
+		respondWithError(w, apierror.Unauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.repos.Sessions.Revoke(r.Context(), sessionID, userID); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to log out")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// POST /logout-all
+func (h *Handler) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, apierror.Unauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.repos.Sessions.RevokeAll(r.Context(), userID, ""); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to log out")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Logged out of all sessions successfully",
+	})
+}
+
+// createSession opens a new session for userID and returns its id together
+// with the refresh token that the client must present to /refresh.
+func (h *Handler) createSession(ctx context.Context, userID string) (sessionID, refreshToken string, err error) {
+	refreshToken, err = utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	sessionID, err = h.repos.Sessions.Create(ctx, userID, utils.HashToken(refreshToken), time.Now().Add(utils.RefreshTokenTTL))
+	if err != nil {
+		return "", "", err
+	}
+	return sessionID, refreshToken, nil
+}
@@ -0,0 +1,59 @@
+package handlers_test
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/handlers"
+	"net/http"
+	"testing"
+)
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	login := ts.signUp("ada@example.com", "secret")
+
+	var first handlers.RefreshResponse
+	status := ts.doJSON(http.MethodPost, "/api/refresh", "", handlers.RefreshRequest{RefreshToken: login.RefreshToken}, &first)
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status %d", status)
+	}
+	if first.RefreshToken == "" || first.RefreshToken == login.RefreshToken {
+		t.Fatalf("refresh token was not rotated")
+	}
+
+	var second handlers.RefreshResponse
+	status = ts.doJSON(http.MethodPost, "/api/refresh", "", handlers.RefreshRequest{RefreshToken: first.RefreshToken}, &second)
+	if status != http.StatusOK {
+		t.Fatalf("refresh with rotated token: status %d", status)
+	}
+	if status := ts.do(http.MethodGet, "/api/getUserData", second.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("access token from refresh: status %d", status)
+	}
+}
+
+func TestRefreshReplayRevokesSession(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	login := ts.signUp("ada@example.com", "secret")
+
+	var rotated handlers.RefreshResponse
+	if status := ts.doJSON(http.MethodPost, "/api/refresh", "", handlers.RefreshRequest{RefreshToken: login.RefreshToken}, &rotated); status != http.StatusOK {
+		t.Fatalf("refresh: status %d", status)
+	}
+
+	// Presenting the old token again means it was copied
+	var apiErr apierror.Error
+	status := ts.doJSON(http.MethodPost, "/api/refresh", "", handlers.RefreshRequest{RefreshToken: login.RefreshToken}, &apiErr)
+	if status != http.StatusUnauthorized || apiErr.Code != apierror.InvalidToken {
+		t.Fatalf("replayed refresh: status %d, code %q", status, apiErr.Code)
+	}
+
+	// The whole session is gone, including the token issued to the legitimate client
+	status = ts.doJSON(http.MethodPost, "/api/refresh", "", handlers.RefreshRequest{RefreshToken: rotated.RefreshToken}, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("refresh after replay: status %d", status)
+	}
+	for _, token := range []string{login.Token, rotated.Token} {
+		if status := ts.do(http.MethodGet, "/api/getUserData", token, "", nil, nil); status != http.StatusUnauthorized {
+			t.Fatalf("access token after replay: status %d", status)
+		}
+	}
+}
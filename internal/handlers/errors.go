@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/repository"
+	"errors"
+	"net/http"
+)
+
+// respondWithError writes an error body with the HTTP status of code.
+func respondWithError(w http.ResponseWriter, code apierror.Code, message string, details ...string) {
+	apierror.Respond(w, code, message, details...)
+}
+
+// respondWithValidation reports which request fields are missing or invalid.
+func respondWithValidation(w http.ResponseWriter, message string, fields ...apierror.FieldError) {
+	apierror.Write(w, apierror.Invalid(message, fields...))
+}
+
+// respondWithAPIError writes err as is if it is an *apierror.Error and as an
+// internal error otherwise.
+func respondWithAPIError(w http.ResponseWriter, err error) {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		apierror.Write(w, apiErr)
+		return
+	}
+	respondWithError(w, apierror.Internal, "Internal server error")
+}
+
+// respondWithUserLookupError reports a failed lookup of a user by id: a
+// missing user is not found, anything else is a database failure.
+func respondWithUserLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.UserNotFound, "User not found")
+		return
+	}
+	respondWithError(w, apierror.Internal, "Failed to look up user")
+}
+
+// missing returns a required-field error for every empty value in the given
+// name, value pairs.
+func missing(namesAndValues ...string) []apierror.FieldError {
+	var fields []apierror.FieldError
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		if namesAndValues[i+1] == "" {
+			fields = append(fields, apierror.Required(namesAndValues[i]))
+		}
+	}
+	return fields
+}
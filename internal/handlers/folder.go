@@ -1,145 +1,223 @@
-package handlers
-
-import (
-	"Backend/internal/db"
-	"Backend/internal/middleware"
-	"encoding/json"
-	"net/http"
-	"time"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
-)
-
-type Folder struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
-type CreateFolderRequest struct {
-	Name string `json:"name"`
-}
-
-type CreateFolderResponse struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-// POST /folders
-func CreateFolderHandler(w http.ResponseWriter, r *http.Request) {
-	// Get authenticated userId from JWT context
-	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok || userId == "" {
-		respondWithError(w, http.StatusUnauthorized, "Invalid user or not logged in")
-		return
-	}
-
-	// Parse request body
-	var req CreateFolderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate folder name
-	if req.Name == "" || len(req.Name) > 255 {
-		respondWithError(w, http.StatusBadRequest, "Folder name is required and must be less than 255 characters")
-		return
-	}
-
-	// Generate folder ID
-	folderID := uuid.New().String()
-
-	// Insert folder into database
-	_, err := db.DB.Exec(
-		"INSERT INTO folders (id, user_id, name) VALUES ($1, $2, $3)",
-		folderID, userId, req.Name,
-	)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create folder")
-		return
-	}
-
-	// Return success response
-	response := CreateFolderResponse{
-		ID:   folderID,
-		Name: req.Name,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
-}
-
-// GET /folders
-func GetFoldersHandler(w http.ResponseWriter, r *http.Request) {
-	// Get authenticated userId from JWT context
-	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok || userId == "" {
-		respondWithError(w, http.StatusUnauthorized, "Invalid user or not logged in")
-		return
-	}
-
-	rows, err := db.DB.Query("SELECT id, user_id, name, created_at, updated_at FROM folders WHERE user_id = $1 ORDER BY created_at DESC", userId)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database query error")
-		return
-	}
-	defer rows.Close()
-
-	var folders []Folder
-	for rows.Next() {
-		var folder Folder
-		if err := rows.Scan(&folder.ID, &folder.UserID, &folder.Name, &folder.CreatedAt, &folder.UpdatedAt); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error scanning folders")
-			return
-		}
-		folders = append(folders, folder)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(folders)
-}
-
-// DELETE /folders/{id}
-func DeleteFolderHandler(w http.ResponseWriter, r *http.Request) {
-	// Get authenticated userId from JWT context
-	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
-	if !ok || userId == "" {
-		respondWithError(w, http.StatusUnauthorized, "Invalid user or not logged in")
-		return
-	}
-
-	folderID := chi.URLParam(r, "id")
-	if folderID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing folder id parameter")
-		return
-	}
-
-	// Check if the folder exists and belongs to the user
-	var exists bool
-	err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM folders WHERE id = $1 AND user_id = $2)", folderID, userId).Scan(&exists)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-	if !exists {
-		respondWithError(w, http.StatusNotFound, "Folder not found or access denied")
-		return
-	}
-
-	// Delete the folder from the database
-	_, err = db.DB.Exec("DELETE FROM folders WHERE id = $1 AND user_id = $2", folderID, userId)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete folder")
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Folder deleted successfully",
-	})
-} 
\ No newline at end of file
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateFolderRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateFolderResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// POST /folders
+func (h *Handler) CreateFolderHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated userId from JWT context
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	// Parse request body
+	var req CreateFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, apierror.BadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate folder name
+	if req.Name == "" {
+		respondWithValidation(w, "Folder name is required and must be less than 255 characters", apierror.Required("name"))
+		return
+	}
+	if len(req.Name) > 255 {
+		respondWithValidation(w, "Folder name is required and must be less than 255 characters", apierror.TooLong("name", 255))
+		return
+	}
+
+	// Generate folder ID
+	folderID := uuid.New().String()
+
+	// Insert folder into database
+	_, err := h.repos.Folders.Create(r.Context(), repository.Folder{ID: folderID, UserID: userId, Name: req.Name})
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to create folder")
+		return
+	}
+
+	// Return success response
+	response := CreateFolderResponse{
+		ID:   folderID,
+		Name: req.Name,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /folders
+//
+// Query parameters: sort (created, the default, or name), order (desc by
+// default, or asc), limit, cursor and q (case-insensitive name match).
+func (h *Handler) GetFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated userId from JWT context
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	q := r.URL.Query()
+	sortName := q.Get("sort")
+	if sortName == "" {
+		sortName = "created"
+	}
+	if sortName != repository.SortCreated && sortName != repository.SortName {
+		respondWithValidation(w, "sort must be created or name", apierror.BadField("sort", "must be created or name"))
+		return
+	}
+	order, err := parseOrder(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	cursor, err := decodeCursor(q.Get("cursor"), sortName, order, repository.ValidFolderSortValue)
+	if err != nil {
+		respondWithError(w, apierror.BadRequest, "Invalid cursor")
+		return
+	}
+
+	fq := repository.FolderQuery{
+		UserID: userId,
+		Name:   q.Get("q"),
+		Sort:   sortName,
+		Desc:   order == "desc",
+		// Fetch one extra row to learn whether another page follows
+		Limit: limit + 1,
+	}
+	if cursor != nil {
+		if _, err := uuid.Parse(cursor.ID); err != nil {
+			respondWithError(w, apierror.BadRequest, "Invalid cursor")
+			return
+		}
+		fq.After = &repository.Cursor{Value: cursor.Value, ID: cursor.ID}
+	}
+
+	folders, err := h.repos.Folders.List(r.Context(), fq)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Database query error")
+		return
+	}
+
+	page := Page[repository.Folder]{Items: folders}
+	if len(folders) > limit {
+		page.Items = folders[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{Sort: sortName, Order: order, Value: repository.FolderSortValue(last, sortName), ID: last.ID})
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// DELETE /folders/{id}
+func (h *Handler) DeleteFolderHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated userId from JWT context
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	folderID := chi.URLParam(r, "id")
+	if folderID == "" {
+		respondWithValidation(w, "Missing folder id parameter", apierror.Required("id"))
+		return
+	}
+
+	// Delete the folder if it exists and belongs to the user
+	err := h.repos.Folders.Delete(r.Context(), folderID, userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.FolderNotFound, "Folder not found or access denied")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to delete folder")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Folder deleted successfully",
+	})
+}
+
+// GET /folders/{id}/images
+//
+// Takes the sort, order, limit and cursor query parameters of GET /images.
+func (h *Handler) GetFolderImagesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get authenticated userId from JWT context
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	folderID := chi.URLParam(r, "id")
+	if folderID == "" {
+		respondWithValidation(w, "Missing folder id parameter", apierror.Required("id"))
+		return
+	}
+
+	owned, err := h.repos.Folders.BelongsTo(r.Context(), folderID, userId)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Database error")
+		return
+	}
+	if !owned {
+		respondWithError(w, apierror.FolderNotFound, "Folder not found or access denied")
+		return
+	}
+
+	sortName, err := parseImageSort(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	order, err := parseOrder(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	iq := repository.ImageQuery{UserID: userId, FolderID: folderID, Sort: sortName, Desc: order == "desc"}
+	page, err := h.listImagePage(r.Context(), iq, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
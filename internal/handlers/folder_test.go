@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/handlers"
+	"net/http"
+	"testing"
+)
+
+func TestMoveAndCopyImagesBetweenFolders(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	holiday := ts.createFolder(owner.Token, "Holiday")
+	best := ts.createFolder(owner.Token, "Best of")
+	a, b := ts.uploadOne(owner.Token), ts.uploadOne(owner.Token)
+
+	req := handlers.MoveImagesRequest{ImageIDs: []string{a.ID, b.ID}, FolderID: holiday}
+	if status := ts.doJSON(http.MethodPost, "/api/images/move", owner.Token, req, nil); status != http.StatusOK {
+		t.Fatalf("move: status %d", status)
+	}
+	if page := ts.listImages(owner.Token, "/api/folders/"+holiday+"/images"); len(page.Items) != 2 {
+		t.Fatalf("holiday has %d images after move, want 2", len(page.Items))
+	}
+	if page := ts.listImages(owner.Token, "/api/images?folder=none"); len(page.Items) != 0 {
+		t.Fatalf("%d images left unfiled after move, want 0", len(page.Items))
+	}
+
+	var copied handlers.MoveImagesResponse
+	req = handlers.MoveImagesRequest{ImageIDs: []string{a.ID}, FolderID: best}
+	if status := ts.doJSON(http.MethodPost, "/api/images/copy", owner.Token, req, &copied); status != http.StatusOK {
+		t.Fatalf("copy: status %d", status)
+	}
+	if len(copied.Images) != 1 || copied.Images[0] == a.ID {
+		t.Fatalf("copy returned %v, want one new id", copied.Images)
+	}
+	if page := ts.listImages(owner.Token, "/api/folders/"+holiday+"/images"); len(page.Items) != 2 {
+		t.Fatalf("holiday has %d images after copy, want 2", len(page.Items))
+	}
+	page := ts.listImages(owner.Token, "/api/folders/"+best+"/images")
+	if len(page.Items) != 1 || page.Items[0].URL != a.Link {
+		t.Fatalf("best of = %+v, want a copy sharing the original's file", page.Items)
+	}
+
+	// The copy shares the original's file, so deleting it keeps the file
+	if status := ts.do(http.MethodDelete, "/api/deleteImages/"+copied.Images[0], owner.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("delete copy: status %d", status)
+	}
+	if keys := ts.deletedKeys(); len(keys) != 0 {
+		t.Fatalf("deleting a copy removed %v from storage", keys)
+	}
+	if status := ts.do(http.MethodDelete, "/api/deleteImages/"+a.ID, owner.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("delete original: status %d", status)
+	}
+	if keys := ts.deletedKeys(); len(keys) == 0 {
+		t.Fatal("deleting the last reference kept the file in storage")
+	}
+}
+
+func TestMoveImagesIsOwnerScoped(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	other := ts.signUp("other@example.com", "secret")
+	ownerFolder := ts.createFolder(owner.Token, "Mine")
+	otherFolder := ts.createFolder(other.Token, "Theirs")
+	mine, theirs := ts.uploadOne(owner.Token), ts.uploadOne(other.Token)
+
+	var apiErr apierror.Error
+	req := handlers.MoveImagesRequest{ImageIDs: []string{mine.ID}, FolderID: otherFolder}
+	status := ts.doJSON(http.MethodPost, "/api/images/move", owner.Token, req, &apiErr)
+	if status != http.StatusNotFound || apiErr.Code != apierror.FolderNotFound {
+		t.Fatalf("move into another user's folder: status %d, code %q", status, apiErr.Code)
+	}
+
+	// One foreign image fails the whole request
+	req = handlers.MoveImagesRequest{ImageIDs: []string{mine.ID, theirs.ID}, FolderID: ownerFolder}
+	status = ts.doJSON(http.MethodPost, "/api/images/copy", owner.Token, req, &apiErr)
+	if status != http.StatusNotFound || apiErr.Code != apierror.ImageNotFound {
+		t.Fatalf("copy another user's image: status %d, code %q", status, apiErr.Code)
+	}
+	if page := ts.listImages(owner.Token, "/api/folders/"+ownerFolder+"/images"); len(page.Items) != 0 {
+		t.Fatalf("a failed copy still filed %d images", len(page.Items))
+	}
+}
+
+func TestDeleteFolderUnfilesImages(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	other := ts.signUp("other@example.com", "secret")
+	folder := ts.createFolder(owner.Token, "Holiday")
+	img := ts.uploadOne(owner.Token)
+	req := handlers.MoveImagesRequest{ImageIDs: []string{img.ID}, FolderID: folder}
+	if status := ts.doJSON(http.MethodPost, "/api/images/move", owner.Token, req, nil); status != http.StatusOK {
+		t.Fatalf("move: status %d", status)
+	}
+
+	if status := ts.do(http.MethodDelete, "/api/folders/"+folder, other.Token, "", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("delete by another user: status %d", status)
+	}
+	if status := ts.do(http.MethodDelete, "/api/folders/"+folder, owner.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("delete folder: status %d", status)
+	}
+	page := ts.listImages(owner.Token, "/api/images?folder=none")
+	if len(page.Items) != 1 || page.Items[0].ID != img.ID {
+		t.Fatalf("unfiled images = %+v, want the image from the deleted folder", page.Items)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"Backend/internal/cleanup"
+	"Backend/internal/health"
+	"Backend/internal/links"
+	"Backend/internal/mail"
+	"Backend/internal/repository"
+	"Backend/internal/storage"
+)
+
+// Deps are the services the HTTP handlers depend on.
+type Deps struct {
+	Repos     repository.Repos
+	Storage   storage.Storage
+	Cleaner   *cleanup.Cleaner
+	Mailer    *mail.Mailer
+	JWTSecret []byte
+	// Verification decides which features wait for a verified email.
+	Verification VerificationPolicy
+	// Links builds the frontend URLs sent to users.
+	Links *links.Builder
+	// DevMode exposes secrets such as reset links in responses for local
+	// testing. Never enable it in production.
+	DevMode bool
+	// Readiness checks the dependencies behind GET /readyz.
+	Readiness *health.Checker
+	Uploads   UploadLimits
+}
+
+// Handler serves the API routes. Build one with New so that tests can swap
+// the database and storage for in-memory implementations.
+type Handler struct {
+	repos        repository.Repos
+	store        storage.Storage
+	cleaner      *cleanup.Cleaner
+	mailer       *mail.Mailer
+	jwtSecret    []byte
+	verification VerificationPolicy
+	links        *links.Builder
+	devMode      bool
+	readiness    *health.Checker
+	uploads      UploadLimits
+	// uploadSlots holds a token for every file being processed
+	uploadSlots chan struct{}
+	// uploadBuffers holds a token for every uploaded image kept in memory,
+	// from when it starts arriving until it has been processed
+	uploadBuffers chan struct{}
+}
+
+func New(deps Deps) *Handler {
+	cleaner := deps.Cleaner
+	if cleaner == nil {
+		cleaner = cleanup.New(deps.Storage, deps.Repos)
+	}
+	if deps.Uploads.Concurrency <= 0 {
+		deps.Uploads.Concurrency = DefaultUploadConcurrency
+	}
+	return &Handler{
+		repos:        deps.Repos,
+		store:        deps.Storage,
+		cleaner:      cleaner,
+		mailer:       deps.Mailer,
+		jwtSecret:    deps.JWTSecret,
+		verification: deps.Verification,
+		links:        deps.Links,
+		devMode:      deps.DevMode,
+		readiness:    deps.Readiness,
+		uploads:      deps.Uploads,
+		uploadSlots:  make(chan struct{}, deps.Uploads.Concurrency),
+		// Enough for every slot to have another image waiting
+		uploadBuffers: make(chan struct{}, 2*deps.Uploads.Concurrency),
+	}
+}
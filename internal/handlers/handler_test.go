@@ -0,0 +1,271 @@
+package handlers_test
+
+import (
+	"Backend/internal/handlers"
+	"Backend/internal/links"
+	"Backend/internal/mail"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"Backend/internal/routes"
+	"Backend/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// testServer serves the API from in-memory repositories and a temporary
+// local storage directory.
+type testServer struct {
+	t     *testing.T
+	srv   *httptest.Server
+	h     *handlers.Handler
+	repos repository.Repos
+	mail  *mail.LogTransport
+	// failPuts makes every storage write fail, as if the backend were down
+	failPuts atomic.Bool
+	// failDeletes does the same for deletes
+	failDeletes atomic.Bool
+
+	mu sync.Mutex
+	// deleted lists every key deleted from storage
+	deleted []string
+}
+
+// deletedKeys returns the keys deleted from storage so far.
+func (ts *testServer) deletedKeys() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]string(nil), ts.deleted...)
+}
+
+// failingStorage fails writes and deletes while its server's failPuts or
+// failDeletes is set, and records successful deletes.
+type failingStorage struct {
+	storage.Storage
+	ts *testServer
+}
+
+func (s failingStorage) Delete(ctx context.Context, key string) error {
+	if s.ts.failDeletes.Load() {
+		return errors.New("storage unavailable")
+	}
+	s.ts.mu.Lock()
+	s.ts.deleted = append(s.ts.deleted, key)
+	s.ts.mu.Unlock()
+	return s.Storage.Delete(ctx, key)
+}
+
+func (s failingStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.Object, error) {
+	if s.ts.failPuts.Load() {
+		return storage.Object{}, errors.New("storage unavailable")
+	}
+	return s.Storage.Put(ctx, key, r, contentType)
+}
+
+func newTestServer(t *testing.T, policy string, limits handlers.UploadLimits) *testServer {
+	t.Helper()
+
+	store, err := storage.NewLocal(t.TempDir(), "http://files.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &mail.LogTransport{Logger: log.New(io.Discard, "", 0)}
+	mailer, err := mail.New(transport, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkBuilder, err := links.New("https://app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verification, err := handlers.ParseVerificationPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &testServer{t: t, repos: repository.NewMemory(), mail: transport}
+	secret := []byte("test-secret")
+	ts.h = handlers.New(handlers.Deps{
+		Repos:        ts.repos,
+		Storage:      failingStorage{Storage: store, ts: ts},
+		Mailer:       mailer,
+		JWTSecret:    secret,
+		Verification: verification,
+		Links:        linkBuilder,
+		DevMode:      true,
+		Uploads:      limits,
+	})
+	auth := middleware.NewAuthMiddleware(secret, ts.repos.Sessions)
+
+	r := chi.NewRouter()
+	r.Route("/api", func(api chi.Router) {
+		routes.RegisterAuthRoutes(api, ts.h, auth)
+		routes.RegisterImageRoutes(api, ts.h, auth)
+		routes.RegisterFolderRoutes(api, ts.h, auth)
+	})
+	ts.srv = httptest.NewServer(r)
+	t.Cleanup(ts.srv.Close)
+	return ts
+}
+
+// do sends a request and decodes the JSON response into out, if given.
+func (ts *testServer) do(method, path, token, contentType string, body io.Reader, out any) int {
+	ts.t.Helper()
+
+	req, err := http.NewRequest(method, ts.srv.URL+path, body)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.srv.Client().Do(req)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			ts.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// doJSON sends v as a JSON body.
+func (ts *testServer) doJSON(method, path, token string, v any, out any) int {
+	ts.t.Helper()
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return ts.do(method, path, token, "application/json", bytes.NewReader(body), out)
+}
+
+// form encodes fields, then each file under "files", as multipart/form-data.
+func form(t *testing.T, fields [][2]string, files ...[2]string) (string, io.Reader) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		fw, err := mw.CreateFormFile("files", f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(fw, f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return mw.FormDataContentType(), &buf
+}
+
+// signUp creates an account and logs it in.
+func (ts *testServer) signUp(email, password string) handlers.LoginResponse {
+	ts.t.Helper()
+
+	fields := [][2]string{{"email", email}, {"password", password}, {"firstName", "Test"}}
+	ct, body := form(ts.t, fields)
+	if status := ts.do(http.MethodPost, "/api/signup", "", ct, body, nil); status != http.StatusCreated {
+		ts.t.Fatalf("signup %s: status %d", email, status)
+	}
+	return ts.login(email, password)
+}
+
+func (ts *testServer) login(email, password string) handlers.LoginResponse {
+	ts.t.Helper()
+
+	ct, body := form(ts.t, [][2]string{{"email", email}, {"password", password}})
+	var resp handlers.LoginResponse
+	if status := ts.do(http.MethodPost, "/api/login", "", ct, body, &resp); status != http.StatusOK {
+		ts.t.Fatalf("login %s: status %d", email, status)
+	}
+	return resp
+}
+
+// createUploadLink creates an upload link for the logged-in user and returns
+// its token.
+func (ts *testServer) createUploadLink(token string, req handlers.CreateUploadLinkRequest) string {
+	ts.t.Helper()
+
+	var resp handlers.CreateUploadLinkResponse
+	if status := ts.doJSON(http.MethodPost, "/api/upload-links", token, req, &resp); status != http.StatusCreated {
+		ts.t.Fatalf("create upload link: status %d", status)
+	}
+	return resp.Token
+}
+
+// testDeviceInfo is the deviceInfo field sent with test uploads.
+const testDeviceInfo = `{"browser":"test"}`
+
+// upload sends files to the guest upload endpoint with uploadToken.
+func (ts *testServer) upload(uploadToken string, files ...[2]string) (int, handlers.UploadResponse) {
+	ts.t.Helper()
+
+	ct, body := form(ts.t, [][2]string{{"uploadToken", uploadToken}, {"deviceInfo", testDeviceInfo}}, files...)
+	var resp handlers.UploadResponse
+	status := ts.do(http.MethodPost, "/api/upload/files", "", ct, body, &resp)
+	return status, resp
+}
+
+// pngFile returns a small valid PNG image named name.
+func pngFile(t *testing.T, name string) [2]string {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return [2]string{name, buf.String()}
+}
+
+// createFolder creates a folder for the logged-in user and returns its id.
+func (ts *testServer) createFolder(token, name string) string {
+	ts.t.Helper()
+
+	var resp handlers.CreateFolderResponse
+	if status := ts.doJSON(http.MethodPost, "/api/folders", token, handlers.CreateFolderRequest{Name: name}, &resp); status != http.StatusCreated {
+		ts.t.Fatalf("create folder: status %d", status)
+	}
+	return resp.ID
+}
+
+// listImages fetches one page of images from path, e.g. /api/images?sort=size.
+func (ts *testServer) listImages(token, path string) handlers.Page[repository.Image] {
+	ts.t.Helper()
+
+	var page handlers.Page[repository.Image]
+	if status := ts.do(http.MethodGet, path, token, "", nil, &page); status != http.StatusOK {
+		ts.t.Fatalf("GET %s: status %d", path, status)
+	}
+	return page
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"Backend/internal/health"
+	"Backend/internal/middleware"
+	"net/http"
+)
+
+// GET /healthz
+// Liveness: the process is up and serving requests. It checks nothing else so
+// that a failing dependency never gets the process restarted.
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"status": health.StatusOK,
+	})
+}
+
+// GET /readyz
+// Readiness: every dependency needed to serve traffic is reachable. The
+// endpoint is public, so failures are logged with the request ID rather than
+// returned, since their messages name internal hosts and ports.
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if h.readiness == nil {
+		respondWithJSON(w, http.StatusOK, health.Report{Status: health.StatusOK, Checks: map[string]health.Result{}})
+		return
+	}
+
+	report := h.readiness.Run(r.Context())
+	for name, res := range report.Checks {
+		if res.Error != "" {
+			middleware.Logger(r.Context()).Warn("readiness check failed", "check", name, "error", res.Error)
+			res.Error = ""
+			report.Checks[name] = res
+		}
+	}
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, status, report)
+}
@@ -1,29 +1,38 @@
 package handlers
 
 import (
-	"Backend/internal/db"
+	"Backend/internal/apierror"
+	"Backend/internal/metrics"
 	"Backend/internal/middleware"
+	"Backend/internal/repository"
 	"Backend/internal/utils"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type SuccessfulFile struct {
-	ID     string `json:"id"`
-	Link   string `json:"link"`
-	UserID string `json:"user_id"`
-	Name   string `json:"name"`
+	ID            string                             `json:"id"`
+	Link          string                             `json:"link"`
+	UserID        string                             `json:"user_id"`
+	Name          string                             `json:"name"`
+	DeletionToken string                             `json:"deletion_token"`
+	Variants      map[string]repository.ImageVariant `json:"variants"`
 }
 
+// FailedFile is a file that was not stored, with the reason why.
 type FailedFile struct {
-	Name string `json:"name"`
+	Name  string        `json:"name"`
+	Code  apierror.Code `json:"code"`
+	Error string        `json:"error"`
 }
 
 type UploadResponse struct {
@@ -35,134 +44,361 @@ type DeleteResponse struct {
 	Message string `json:"message"`
 }
 
-type Image struct {
-	ID     string `json:"id"`
-	UserID string `json:"user_id"`
-	URL    string `json:"url"`
+// TimelineDay groups images by the day they were taken. Images without a
+// capture time are grouped by the day they were uploaded.
+type TimelineDay struct {
+	Date   string             `json:"date"`
+	Count  int                `json:"count"`
+	Images []repository.Image `json:"images"`
 }
 
+type MoveImagesRequest struct {
+	ImageIDs []string `json:"imageIds"`
+	FolderID string   `json:"folderId"`
+}
+
+type MoveImagesResponse struct {
+	Message string   `json:"message"`
+	Images  []string `json:"images"`
+}
 
 // GET  /images
-func GetImagesHandler(w http.ResponseWriter, r *http.Request) {
+//
+// Query parameters:
+//   - sort: uploaded (default), captured or size
+//   - order: desc (default) or asc
+//   - limit: page size, default 50, max 200
+//   - cursor: next_cursor from the previous page
+//   - folder: folder id, or "none" for unfiled images
+//   - uploaded_from, uploaded_to, captured_from, captured_to: RFC 3339 or
+//     YYYY-MM-DD; "from" is inclusive, "to" is exclusive
+//   - device: case-insensitive match against the uploader's device info
+//   - uploader: id of the upload link the images arrived through
+func (h *Handler) GetImagesHandler(w http.ResponseWriter, r *http.Request) {
 	// Get authenticated userId from JWT context
 	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userId == "" {
-		respondWithError(w, http.StatusUnauthorized, "Invalid user or not logged in")
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT id, user_id, image_url FROM images WHERE user_id = $1", userId)
+	q := r.URL.Query()
+	sortName, err := parseImageSort(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	order, err := parseOrder(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database query error")
+		respondWithAPIError(w, err)
 		return
 	}
-	defer rows.Close()
+	limit, err := parseLimit(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	iq := repository.ImageQuery{
+		UserID: userId,
+		Device: q.Get("device"),
+		Sort:   sortName,
+		Desc:   order == "desc",
+	}
 
-	var images []Image
-	for rows.Next() {
-		var img Image
-		if err := rows.Scan(&img.ID, &img.UserID, &img.URL); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error scanning images")
+	switch folder := q.Get("folder"); folder {
+	case "":
+	case "none":
+		iq.Unfiled = true
+	default:
+		if _, err := uuid.Parse(folder); err != nil {
+			respondWithValidation(w, "Invalid folder filter", apierror.BadField("folder", "must be a folder id or none"))
 			return
 		}
-		images = append(images, img)
+		iq.FolderID = folder
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(images)
+	timeFilters := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"uploaded_from", &iq.UploadedFrom},
+		{"uploaded_to", &iq.UploadedTo},
+		{"captured_from", &iq.CapturedFrom},
+		{"captured_to", &iq.CapturedTo},
+	}
+	for _, f := range timeFilters {
+		raw := q.Get(f.param)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeFilter(raw)
+		if err != nil {
+			respondWithValidation(w, "Invalid "+f.param, apierror.BadField(f.param, "use RFC 3339 or YYYY-MM-DD"))
+			return
+		}
+		*f.dest = &t
+	}
+
+	if uploader := q.Get("uploader"); uploader != "" {
+		if _, err := uuid.Parse(uploader); err != nil {
+			respondWithValidation(w, "Invalid uploader filter", apierror.BadField("uploader", "must be an upload link id"))
+			return
+		}
+		iq.UploadLinkID = uploader
+	}
+
+	page, err := h.listImagePage(r.Context(), iq, q.Get("cursor"), limit)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
 }
 
-// POST /images/{userId}
-func AddImageHandler(w http.ResponseWriter, r *http.Request) {
+// parseImageSort reads the sort query parameter of image listings.
+func parseImageSort(r *http.Request) (string, error) {
+	switch sortName := r.URL.Query().Get("sort"); sortName {
+	case "":
+		return repository.SortUploaded, nil
+	case repository.SortUploaded, repository.SortCaptured, repository.SortSize:
+		return sortName, nil
+	default:
+		return "", apierror.Invalid("sort must be uploaded, captured or size", apierror.BadField("sort", "must be uploaded, captured or size"))
+	}
+}
 
-	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+// listImagePage fetches the page of images iq selects that follows rawCursor.
+// iq.Sort and iq.Desc must already be set, since the cursor encodes them.
+func (h *Handler) listImagePage(ctx context.Context, iq repository.ImageQuery, rawCursor string, limit int) (Page[repository.Image], error) {
+	order := "asc"
+	if iq.Desc {
+		order = "desc"
+	}
+	cursor, err := decodeCursor(rawCursor, iq.Sort, order, repository.ValidImageSortValue)
+	if err != nil {
+		return Page[repository.Image]{}, apierror.New(apierror.BadRequest, "Invalid cursor")
+	}
+	if cursor != nil {
+		iq.After = &repository.Cursor{Value: cursor.Value, ID: cursor.ID}
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	iq.Limit = limit + 1
+	images, err := h.repos.Images.List(ctx, iq)
+	if err != nil {
+		return Page[repository.Image]{}, apierror.New(apierror.Internal, "Database query error")
+	}
+
+	page := Page[repository.Image]{Items: images}
+	if len(images) > limit {
+		page.Items = images[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(pageCursor{Sort: iq.Sort, Order: order, Value: repository.ImageSortValue(last, iq.Sort), ID: last.ID})
+	}
+	return page, nil
+}
+
+// parseTimeFilter accepts an RFC 3339 timestamp or a plain date (midnight UTC).
+func parseTimeFilter(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+// GET /images/timeline
+//
+// Takes the order, limit and cursor query parameters of GET /images; limit
+// counts images, not days. A day can continue on the next page, so clients
+// merge a page's first day into the previous page's last one when the dates
+// match.
+func (h *Handler) GetImageTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
 		return
 	}
 
-	// Parse multipart form (allowing up to 32 MB in memory)
-	err := r.ParseMultipartForm(32 << 20)
+	order, err := parseOrder(r)
+	if err != nil {
+		respondWithAPIError(w, err)
+		return
+	}
+	limit, err := parseLimit(r)
 	if err != nil {
-		AddFilerespondWithError(w, http.StatusBadRequest, "Error parsing form")
+		respondWithAPIError(w, err)
 		return
 	}
 
-	// Get userId and deviceInfo from form data
-	userId := r.FormValue("userId")
-	deviceInfo := r.FormValue("deviceInfo")
-	if userId == "" || deviceInfo == "" {
-		AddFilerespondWithError(w, http.StatusBadRequest, "Missing userId or deviceInfo")
+	iq := repository.ImageQuery{UserID: userId, Sort: repository.SortCaptured, Desc: order == "desc"}
+	page, err := h.listImagePage(r.Context(), iq, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		respondWithAPIError(w, err)
 		return
 	}
 
-	// Get all uploaded files (files[])
-	files := r.MultipartForm.File["files"]
-	if len(files) == 0 {
-		AddFilerespondWithError(w, http.StatusBadRequest, "No files uploaded!")
+	days := []TimelineDay{}
+	for _, img := range page.Items {
+		taken := img.CreatedAt.UTC()
+		if img.CapturedAt != nil {
+			taken = *img.CapturedAt
+		}
+		date := taken.Format(time.DateOnly)
+
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, TimelineDay{Date: date})
+		}
+		day := &days[len(days)-1]
+		day.Images = append(day.Images, img)
+		day.Count++
+	}
+
+	respondWithJSON(w, http.StatusOK, Page[TimelineDay]{Items: days, NextCursor: page.NextCursor})
+}
+
+// POST /upload/files
+func (h *Handler) AddImageHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		respondWithError(w, apierror.MethodNotAllowed, "Only POST allowed")
 		return
 	}
 
-	var successfulFiles []SuccessfulFile
-	var failedFiles []FailedFile
+	if h.uploads.MaxRequestBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.uploads.MaxRequestBytes)
+	}
 
-	// Helper function to add failed file
-	addFailedFile := func(filename string) {
-		failedFiles = append(failedFiles, FailedFile{Name: filename})
+	// Read the form part by part so files stream straight into storage
+	// instead of being buffered in memory or spooled to temporary files
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondWithError(w, apierror.BadRequest, "Expected a multipart/form-data body")
+		return
 	}
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
+	var (
+		fields  = map[string]string{}
+		target  *uploadTarget
+		logger  = middleware.Logger(r.Context())
+		results []*uploadResult
+		wg      sync.WaitGroup
+		// backlog bounds the received files of this request still waiting
+		// to be saved, so one request can't take every upload buffer
+		backlog = make(chan struct{}, cap(h.uploadSlots))
+	)
+	defer wg.Wait()
+
+parts:
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
-			addFailedFile(fileHeader.Filename)
-			continue
+			if target == nil {
+				respondWithFormError(w, err)
+				return
+			}
+			// The files already received are still reported
+			logger.Warn("read upload form", "error", err)
+			break
 		}
 
-		// Save to temp location
-		tmpDir := os.TempDir()
-		tmpFile := filepath.Join(tmpDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), fileHeader.Filename))
-		out, err := os.Create(tmpFile)
-		if err != nil {
-			file.Close()
-			addFailedFile(fileHeader.Filename)
+		// Text fields have to come before the files they describe. Later
+		// ones are skipped unread, so they can't fail a request whose first
+		// files are already stored
+		if part.FileName() == "" {
+			if target != nil {
+				continue
+			}
+			name := part.FormName()
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes+1))
+			if err != nil {
+				respondWithFormError(w, err)
+				return
+			}
+			if len(value) > maxFieldBytes {
+				respondWithValidation(w, "Form field is too long", apierror.TooLong(name, maxFieldBytes))
+				return
+			}
+			fields[name] = string(value)
 			continue
 		}
-
-		_, err = io.Copy(out, file)
-		out.Close()
-		file.Close()
-		if err != nil {
-			os.Remove(tmpFile)
-			addFailedFile(fileHeader.Filename)
+		if part.FormName() != "files" {
 			continue
 		}
 
-		// Upload to Cloudinary
-		imageUrl, err := utils.UploadToCloudinary(tmpFile)
-		os.Remove(tmpFile)
-		if err != nil {
-			addFailedFile(fileHeader.Filename)
-			continue
+		if target == nil {
+			t, err := h.resolveUploadTarget(r.Context(), fields)
+			if err != nil {
+				respondWithAPIError(w, err)
+				return
+			}
+			target = &t
+			logger = logger.With("upload_link_id", t.LinkID, "user_id", t.UserID)
 		}
 
-		// Insert into DB
-		var id string
-		err = db.DB.QueryRow(
-			"INSERT INTO images (user_id, image_url, device_info) VALUES ($1, $2, $3) RETURNING id",
-			userId, imageUrl, deviceInfo,
-		).Scan(&id)
-		if err != nil {
-			addFailedFile(fileHeader.Filename)
+		res := &uploadResult{name: cleanFileName(part.FileName())}
+		results = append(results, res)
+
+		select {
+		case backlog <- struct{}{}:
+		case <-r.Context().Done():
+			res.err = cancelled(r.Context().Err())
+			break parts
+		}
+		rf, ferr := h.receiveUpload(r.Context(), *target, res.name, part)
+		if ferr != nil {
+			<-backlog
+			res.err = ferr
+			metrics.ObserveUpload(uploadOutcome(ferr), rf.size)
+			var maxErr *http.MaxBytesError
+			if errors.As(ferr, &maxErr) || r.Context().Err() != nil {
+				// Nothing more can be read from the body
+				break parts
+			}
 			continue
 		}
 
-		// Add to successful files
-		successfulFile := SuccessfulFile{
-			ID:     id,
-			UserID: userId,
-			Link:   imageUrl,
-			Name:   fileHeader.Filename,
+		// Parts arrive one after another, but variants and the database
+		// record can be done while the next file is being received. Only
+		// this work takes one of the server-wide upload slots, so guests on
+		// slow connections don't hold them while their files trickle in
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-backlog }()
+			res.file, res.err = h.finishUpload(r.Context(), *target, rf)
+			if res.err != nil {
+				metrics.ObserveUpload(uploadOutcome(res.err), rf.size)
+				return
+			}
+			metrics.ObserveUpload(metrics.UploadStored, rf.size)
+		}()
+	}
+	wg.Wait()
+
+	if target == nil {
+		// Report missing fields or a bad link before the missing files
+		if _, err := h.resolveUploadTarget(r.Context(), fields); err != nil {
+			respondWithAPIError(w, err)
+			return
+		}
+		respondWithValidation(w, "No files uploaded!", apierror.Required("files"))
+		return
+	}
+
+	successfulFiles := []SuccessfulFile{}
+	failedFiles := []FailedFile{}
+	for _, res := range results {
+		if res.err != nil {
+			logger.Warn("upload file failed", "file", res.name, "code", res.err.Code, "error", res.err)
+			failedFiles = append(failedFiles, FailedFile{Name: res.name, Code: res.err.Code, Error: res.err.Message})
+			continue
 		}
-		successfulFiles = append(successfulFiles, successfulFile)
+		successfulFiles = append(successfulFiles, res.file)
 	}
 
 	// Create structured response
@@ -170,54 +406,225 @@ func AddImageHandler(w http.ResponseWriter, r *http.Request) {
 		Successful: successfulFiles,
 		Failed:     failedFiles,
 	}
+	respondWithJSON(w, uploadStatus(response), response)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// resolveUploadTarget checks the text fields of an upload request and works
+// out where its files are filed.
+func (h *Handler) resolveUploadTarget(ctx context.Context, fields map[string]string) (uploadTarget, error) {
+	uploadToken := fields["uploadToken"]
+	deviceInfo := fields["deviceInfo"]
+	if uploadToken == "" || deviceInfo == "" {
+		return uploadTarget{}, apierror.Invalid("Missing uploadToken or deviceInfo; send them before the files",
+			missing("uploadToken", uploadToken, "deviceInfo", deviceInfo)...)
+	}
+	// It is stored as JSON, so reject anything else before any file is stored
+	if !json.Valid([]byte(deviceInfo)) {
+		return uploadTarget{}, apierror.Invalid("deviceInfo must be a JSON value",
+			apierror.BadField("deviceInfo", "must be valid JSON"))
+	}
+
+	link, err := h.activeUploadLink(ctx, uploadToken)
+	if errors.Is(err, repository.ErrNotFound) {
+		return uploadTarget{}, apierror.New(apierror.UploadLinkInvalid, "Upload link is invalid, expired or revoked")
+	}
+	if err != nil {
+		return uploadTarget{}, apierror.New(apierror.Internal, "Database error")
+	}
+
+	// The link's folder wins; otherwise an optional folder owned by the link's user
+	folderID := link.FolderID
+	if f := fields["folderId"]; folderID == nil && f != "" {
+		owned, err := h.repos.Folders.BelongsTo(ctx, f, link.UserID)
+		if err != nil {
+			return uploadTarget{}, apierror.New(apierror.Internal, "Database error")
+		}
+		if !owned {
+			return uploadTarget{}, apierror.New(apierror.FolderNotFound, "Folder not found or access denied")
+		}
+		folderID = &f
+	}
+
+	return uploadTarget{
+		UserID:     link.UserID,
+		FolderID:   folderID,
+		LinkID:     link.ID,
+		DeviceInfo: deviceInfo,
+	}, nil
 }
 
+// respondWithFormError reports an upload form that could not be read.
+func respondWithFormError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		respondWithError(w, apierror.UploadTooLarge, fmt.Sprintf("The request is larger than the %d byte limit", maxErr.Limit))
+		return
+	}
+	respondWithError(w, apierror.BadRequest, "Error parsing form")
+}
 
-func AddFilerespondWithError(w http.ResponseWriter, code int, message ...string) {
-	w.WriteHeader(code)
-	msg := "error"
-	if len(message) > 0 {
-		msg = message[0]
-		if len(message) > 1 {
-			msg += ": " + message[1]
+// uploadOutcome is the metrics outcome of a file that was not stored.
+func uploadOutcome(err *fileError) string {
+	if err.Code.Status() < 500 && err.Code != apierror.UploadCancelled {
+		return metrics.UploadRejected
+	}
+	return metrics.UploadFailed
+}
+
+// uploadStatus is 200 when every file was stored and 207 when only some
+// were. When none were, it is 400 if every failure was caused by the request
+// and otherwise the status of the first server-side failure, e.g. 502 while
+// storage is down.
+func uploadStatus(resp UploadResponse) int {
+	switch {
+	case len(resp.Failed) == 0:
+		return http.StatusOK
+	case len(resp.Successful) > 0:
+		return http.StatusMultiStatus
+	}
+	for _, f := range resp.Failed {
+		if status := f.Code.Status(); status >= 500 {
+			return status
 		}
 	}
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	return http.StatusBadRequest
 }
 
+// DELETE /deleteImages/{id}
+func (h *Handler) DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
 
-// DELETE /images/{id}
-func DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing image id parameter")
+		respondWithValidation(w, "Missing image id parameter", apierror.Required("id"))
 		return
 	}
 
-	// Check if the image exists (optional but nice for UX)
-	var exists bool
-	err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM images WHERE id = $1)", id).Scan(&exists)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database error")
+	h.deleteImage(w, r, func(ctx context.Context) ([]string, error) {
+		return h.repos.Images.Delete(ctx, id, userId)
+	})
+}
+
+// DELETE /upload/files/{id}
+// Lets a guest delete their own upload with the deletion token returned by AddImageHandler.
+func (h *Handler) GuestDeleteImageHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		respondWithValidation(w, "Missing image id parameter", apierror.Required("id"))
 		return
 	}
-	if !exists {
-		respondWithError(w, http.StatusNotFound, "Image not found")
+
+	token := r.Header.Get("X-Deletion-Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		respondWithError(w, apierror.Unauthorized, "Deletion token is required")
 		return
 	}
 
-	// Delete the image from the database
-	_, err = db.DB.Exec("DELETE FROM images WHERE id = $1", id)
+	h.deleteImage(w, r, func(ctx context.Context) ([]string, error) {
+		return h.repos.Images.DeleteWithToken(ctx, id, utils.HashToken(token))
+	})
+}
+
+// deleteImage runs a scoped delete and removes the image's assets.
+// Images outside the caller's scope are reported as not found.
+func (h *Handler) deleteImage(w http.ResponseWriter, r *http.Request, del func(context.Context) ([]string, error)) {
+	// The repository only returns keys that no copy of the image still references
+	keys, err := del(r.Context())
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.ImageNotFound, "Image not found")
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete image")
+		respondWithError(w, apierror.Internal, "Failed to delete image")
 		return
 	}
 
+	// Failures are queued for the background sweeper
+	for _, key := range keys {
+		_ = h.cleaner.DeleteAsset(r.Context(), key)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(DeleteResponse{
 		Message: "Image deleted successfully",
 	})
 }
+
+// POST /images/move
+func (h *Handler) MoveImagesHandler(w http.ResponseWriter, r *http.Request) {
+	h.reassignImages(w, r, false)
+}
+
+// POST /images/copy
+func (h *Handler) CopyImagesHandler(w http.ResponseWriter, r *http.Request) {
+	h.reassignImages(w, r, true)
+}
+
+// reassignImages moves or copies the requested images into a folder. An empty
+// folderId targets the unfiled root. Copies share the original storage asset.
+func (h *Handler) reassignImages(w http.ResponseWriter, r *http.Request, copyImages bool) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	var req MoveImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, apierror.BadRequest, "Invalid request body")
+		return
+	}
+	if len(req.ImageIDs) == 0 {
+		respondWithValidation(w, "imageIds is required", apierror.Required("imageIds"))
+		return
+	}
+	for _, id := range req.ImageIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			respondWithValidation(w, "Invalid image id", apierror.BadField("imageIds", id+" is not a valid id"))
+			return
+		}
+	}
+
+	if req.FolderID != "" {
+		owned, err := h.repos.Folders.BelongsTo(r.Context(), req.FolderID, userId)
+		if err != nil {
+			respondWithError(w, apierror.Internal, "Database error")
+			return
+		}
+		if !owned {
+			respondWithError(w, apierror.FolderNotFound, "Folder not found or access denied")
+			return
+		}
+	}
+
+	// Every image must belong to the user, otherwise nothing is changed
+	reassign := h.repos.Images.Move
+	if copyImages {
+		reassign = h.repos.Images.Copy
+	}
+	ids, err := reassign(r.Context(), userId, req.ImageIDs, req.FolderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.ImageNotFound, "One or more images not found or access denied")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to update images")
+		return
+	}
+
+	message := "Images moved successfully"
+	if copyImages {
+		message = "Images copied successfully"
+	}
+	respondWithJSON(w, http.StatusOK, MoveImagesResponse{
+		Message: message,
+		Images:  ids,
+	})
+}
@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/handlers"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uploadOne uploads a single image through a new upload link of the
+// logged-in user and returns it.
+func (ts *testServer) uploadOne(token string) handlers.SuccessfulFile {
+	ts.t.Helper()
+
+	uploadToken := ts.createUploadLink(token, handlers.CreateUploadLinkRequest{Name: "party"})
+	status, resp := ts.upload(uploadToken, pngFile(ts.t, "photo.png"))
+	if status != http.StatusOK || len(resp.Successful) != 1 {
+		ts.t.Fatalf("upload: status %d, response %+v", status, resp)
+	}
+	return resp.Successful[0]
+}
+
+func TestDeleteImageIsOwnerScoped(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	other := ts.signUp("other@example.com", "secret")
+	img := ts.uploadOne(owner.Token)
+
+	var apiErr apierror.Error
+	status := ts.do(http.MethodDelete, "/api/deleteImages/"+img.ID, other.Token, "", nil, &apiErr)
+	if status != http.StatusNotFound || apiErr.Code != apierror.ImageNotFound {
+		t.Fatalf("delete by another user: status %d, code %q", status, apiErr.Code)
+	}
+
+	if status := ts.do(http.MethodDelete, "/api/deleteImages/"+img.ID, owner.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("delete by owner: status %d", status)
+	}
+	if status := ts.do(http.MethodDelete, "/api/deleteImages/"+img.ID, owner.Token, "", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("second delete by owner: status %d", status)
+	}
+}
+
+func TestGuestDeleteNeedsDeletionToken(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	img := ts.uploadOne(owner.Token)
+	if img.DeletionToken == "" {
+		t.Fatal("upload returned no deletion token")
+	}
+	path := "/api/upload/files/" + img.ID
+
+	if status := ts.do(http.MethodDelete, path, "", "", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("guest delete without token: status %d", status)
+	}
+	// Another guest's token doesn't reach this image
+	otherImg := ts.uploadOne(owner.Token)
+	if status := ts.do(http.MethodDelete, path+"?token="+url.QueryEscape(otherImg.DeletionToken), "", "", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("guest delete with another image's token: status %d", status)
+	}
+
+	if status := ts.do(http.MethodDelete, path+"?token="+url.QueryEscape(img.DeletionToken), "", "", nil, nil); status != http.StatusOK {
+		t.Fatalf("guest delete with token: status %d", status)
+	}
+	if status := ts.do(http.MethodDelete, "/api/deleteImages/"+img.ID, owner.Token, "", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("owner delete after guest delete: status %d", status)
+	}
+}
+
+func TestUploadStatus(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party"})
+	text := [2]string{"notes.png", "just some text, whatever the name says"}
+
+	tests := []struct {
+		name       string
+		files      [][2]string
+		failPuts   bool
+		wantStatus int
+		wantStored int
+		wantCode   apierror.Code
+	}{
+		{"all stored", [][2]string{pngFile(t, "a.png"), pngFile(t, "b.png")}, false, http.StatusOK, 2, ""},
+		{"some stored", [][2]string{pngFile(t, "a.png"), text}, false, http.StatusMultiStatus, 1, apierror.UploadUnsupportedType},
+		{"none stored, client's fault", [][2]string{text, text}, false, http.StatusBadRequest, 0, apierror.UploadUnsupportedType},
+		{"none stored, storage down", [][2]string{pngFile(t, "a.png"), text}, true, http.StatusBadGateway, 0, apierror.UploadStorageFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.failPuts.Store(tt.failPuts)
+			defer ts.failPuts.Store(false)
+
+			status, resp := ts.upload(uploadToken, tt.files...)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if len(resp.Successful) != tt.wantStored || len(resp.Failed) != len(tt.files)-tt.wantStored {
+				t.Fatalf("stored %d and failed %d, want %d stored", len(resp.Successful), len(resp.Failed), tt.wantStored)
+			}
+			if tt.wantCode != "" && resp.Failed[0].Code != tt.wantCode {
+				t.Errorf("failure code = %q, want %q", resp.Failed[0].Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUploadRejectsFieldsAfterFiles(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party"})
+
+	// The form helper writes fields first, so build this body by hand
+	body := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"files\"; filename=\"a.png\"\r\n\r\n" + pngFile(t, "a.png")[1] + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"uploadToken\"\r\n\r\n" + uploadToken + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"deviceInfo\"\r\n\r\n" + testDeviceInfo + "\r\n" +
+		"--b--\r\n"
+
+	var apiErr apierror.Error
+	status := ts.do(http.MethodPost, "/api/upload/files", "", "multipart/form-data; boundary=b", strings.NewReader(body), &apiErr)
+	if status != http.StatusBadRequest || apiErr.Code != apierror.Validation {
+		t.Fatalf("status %d, code %q", status, apiErr.Code)
+	}
+}
+
+func TestUploadRejectsDeviceInfoThatIsNotJSON(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party"})
+
+	ct, body := form(t, [][2]string{{"uploadToken", uploadToken}, {"deviceInfo", "Pixel 8"}}, pngFile(t, "a.png"))
+	var apiErr apierror.Error
+	status := ts.do(http.MethodPost, "/api/upload/files", "", ct, body, &apiErr)
+	if status != http.StatusBadRequest || apiErr.Code != apierror.Validation {
+		t.Fatalf("status %d, code %q", status, apiErr.Code)
+	}
+	if len(apiErr.Fields) != 1 || apiErr.Fields[0].Field != "deviceInfo" {
+		t.Fatalf("fields = %+v, want deviceInfo", apiErr.Fields)
+	}
+}
+
+func TestConcurrentUploadsShareBuffers(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{Concurrency: 1})
+	owner := ts.signUp("owner@example.com", "secret")
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party"})
+
+	// More images than buffers, from several requests at once; every buffer
+	// has to be given back for all of them to get through
+	var wg sync.WaitGroup
+	statuses := make([]int, 4)
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ct, body := form(t, [][2]string{{"uploadToken", uploadToken}, {"deviceInfo", testDeviceInfo}},
+				pngFile(t, "a.png"), pngFile(t, "b.png"), pngFile(t, "c.png"))
+			req, _ := http.NewRequest(http.MethodPost, ts.srv.URL+"/api/upload/files", body)
+			req.Header.Set("Content-Type", ct)
+			resp, err := ts.srv.Client().Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("request %d: status %d", i, status)
+		}
+	}
+}
+
+func TestUploadSkipsFieldsAfterFiles(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party"})
+
+	// A field too long to accept arrives once the first file is stored
+	body := "--b\r\nContent-Disposition: form-data; name=\"uploadToken\"\r\n\r\n" + uploadToken + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"deviceInfo\"\r\n\r\n" + testDeviceInfo + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"files\"; filename=\"a.png\"\r\n\r\n" + pngFile(t, "a.png")[1] + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"notes\"\r\n\r\n" + strings.Repeat("x", 128<<10) + "\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"files\"; filename=\"b.png\"\r\n\r\n" + pngFile(t, "b.png")[1] + "\r\n" +
+		"--b--\r\n"
+
+	var resp handlers.UploadResponse
+	status := ts.do(http.MethodPost, "/api/upload/files", "", "multipart/form-data; boundary=b", strings.NewReader(body), &resp)
+	if status != http.StatusOK || len(resp.Successful) != 2 {
+		t.Fatalf("status %d, response %+v", status, resp)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"Backend/internal/handlers"
+	"Backend/internal/repository"
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+// walkPages follows next_cursor from path until the last page and returns
+// every item in order.
+func walkPages[T any](ts *testServer, token, path string) []T {
+	ts.t.Helper()
+
+	var items []T
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			ts.t.Fatalf("GET %s: pagination did not end", path)
+		}
+		u := path
+		if cursor != "" {
+			u += "&cursor=" + url.QueryEscape(cursor)
+		}
+		var page handlers.Page[T]
+		if status := ts.do(http.MethodGet, u, token, "", nil, &page); status != http.StatusOK {
+			ts.t.Fatalf("GET %s: status %d", u, status)
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func TestImagesPaginateBySize(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+
+	// Repeated sizes must neither be skipped nor repeated across pages
+	sizes := []int64{300, 100, 200, 100, 300, 100, 50}
+	for _, size := range sizes {
+		if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/x", SizeBytes: &size}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, order := range []string{"asc", "desc"} {
+		images := walkPages[repository.Image](ts, owner.Token, "/api/images?sort=size&limit=2&order="+order)
+		if len(images) != len(sizes) {
+			t.Fatalf("%s: got %d images, want %d", order, len(images), len(sizes))
+		}
+
+		seen := map[string]bool{}
+		var got []int64
+		for _, img := range images {
+			if seen[img.ID] {
+				t.Errorf("%s: image %s listed twice", order, img.ID)
+			}
+			seen[img.ID] = true
+			got = append(got, *img.SizeBytes)
+		}
+		want := slices.Clone(sizes)
+		slices.Sort(want)
+		if order == "desc" {
+			slices.Reverse(want)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: sizes %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestImagesPaginateByUpload(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+	other := ts.signUp("other@example.com", "correct horse battery")
+
+	var ids []string
+	for range 5 {
+		img, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/x"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, img.ID)
+	}
+	if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: other.UserID, URL: "http://files.test/y"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	images := walkPages[repository.Image](ts, owner.Token, "/api/images?limit=2")
+	var got []string
+	for i, img := range images {
+		// Newest first by default
+		if i > 0 && img.CreatedAt.After(images[i-1].CreatedAt) {
+			t.Errorf("image %d uploaded after image %d", i, i-1)
+		}
+		got = append(got, img.ID)
+	}
+	slices.Sort(got)
+	slices.Sort(ids)
+	if !slices.Equal(got, ids) {
+		t.Errorf("ids %v, want %v", got, ids)
+	}
+}
+
+func TestFoldersPaginateByName(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+
+	names := []string{"Wedding", "Beach", "Party", "Beach", "Ceremony"}
+	for _, name := range names {
+		ts.createFolder(owner.Token, name)
+	}
+
+	var got []string
+	for _, f := range walkPages[repository.Folder](ts, owner.Token, "/api/folders?sort=name&order=asc&limit=2") {
+		got = append(got, f.Name)
+	}
+	want := slices.Clone(names)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("names %v, want %v", got, want)
+	}
+
+	var page handlers.Page[repository.Folder]
+	if status := ts.do(http.MethodGet, "/api/folders?q=bea", owner.Token, "", nil, &page); status != http.StatusOK {
+		t.Fatalf("search: status %d", status)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("search for bea found %d folders, want 2", len(page.Items))
+	}
+}
+
+func TestPaginationRejectsBadParameters(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+
+	for range 3 {
+		ts.createFolder(owner.Token, "Folder")
+		if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/x"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	imageCursor := ts.listImages(owner.Token, "/api/images?sort=size&limit=1").NextCursor
+	var folders handlers.Page[repository.Folder]
+	ts.do(http.MethodGet, "/api/folders?limit=1", owner.Token, "", nil, &folders)
+	if imageCursor == "" || folders.NextCursor == "" {
+		t.Fatal("expected a second page")
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"limit zero", "/api/images?limit=0"},
+		{"limit not a number", "/api/images?limit=ten"},
+		{"unknown sort", "/api/images?sort=name"},
+		{"unknown order", "/api/images?order=up"},
+		{"garbage cursor", "/api/images?cursor=garbage"},
+		{"cursor for another sort", "/api/images?sort=uploaded&cursor=" + imageCursor},
+		{"cursor for another order", "/api/images?sort=size&order=asc&cursor=" + imageCursor},
+		{"image cursor on folders", "/api/folders?cursor=" + imageCursor},
+		{"folder cursor on images", "/api/images?cursor=" + folders.NextCursor},
+		{"unknown folder sort", "/api/folders?sort=size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do(http.MethodGet, tt.path, owner.Token, "", nil, nil); status != http.StatusBadRequest {
+				t.Errorf("GET %s: status %d, want 400", tt.path, status)
+			}
+		})
+	}
+}
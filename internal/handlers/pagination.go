@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Page is the envelope returned by every paginated list endpoint.
+// NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// pageCursor marks the last row of a page: its sort value and id, which
+// together give a stable keyset position even when sort values repeat.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same sort and
+// order, since keyset positions are meaningless across orderings. validValue
+// checks the sort value, so a tampered cursor is rejected here rather than by
+// the database.
+func decodeCursor(raw, sort, order string, validValue func(sort, value string) bool) (*pageCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errInvalidCursor
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, errInvalidCursor
+	}
+	if _, err := uuid.Parse(c.ID); err != nil || !validValue(sort, c.Value) {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
+
+// parseLimit reads the limit query parameter, defaulting and capping it.
+func parseLimit(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultPageSize, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, apierror.Invalid("limit must be a positive integer", apierror.BadField("limit", "must be a positive integer"))
+	}
+	return min(n, maxPageSize), nil
+}
+
+// parseOrder reads the order query parameter ("asc" or "desc", default "desc").
+func parseOrder(r *http.Request) (string, error) {
+	switch order := r.URL.Query().Get("order"); order {
+	case "":
+		return "desc", nil
+	case "asc", "desc":
+		return order, nil
+	default:
+		return "", apierror.Invalid("order must be asc or desc", apierror.BadField("order", "must be asc or desc"))
+	}
+}
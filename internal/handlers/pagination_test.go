@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"Backend/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	id := uuid.NewString()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name    string
+		raw     string
+		sort    string
+		valid   func(sort, value string) bool
+		wantErr bool
+	}{
+		{"empty", "", repository.SortUploaded, repository.ValidImageSortValue, false},
+		{"uploaded", encodeCursor(pageCursor{Sort: "uploaded", Order: "desc", Value: created, ID: id}), "uploaded", repository.ValidImageSortValue, false},
+		{"size", encodeCursor(pageCursor{Sort: "size", Order: "desc", Value: "1024", ID: id}), "size", repository.ValidImageSortValue, false},
+		{"captured", encodeCursor(pageCursor{Sort: "captured", Order: "desc", Value: "2024-05-01T12:00:00", ID: id}), "captured", repository.ValidImageSortValue, false},
+		{"folder name", encodeCursor(pageCursor{Sort: "name", Order: "desc", Value: "Holiday", ID: id}), "name", repository.ValidFolderSortValue, false},
+		{"not base64", "!!!", "uploaded", repository.ValidImageSortValue, true},
+		{"other sort", encodeCursor(pageCursor{Sort: "size", Order: "desc", Value: "1024", ID: id}), "uploaded", repository.ValidImageSortValue, true},
+		{"other order", encodeCursor(pageCursor{Sort: "size", Order: "asc", Value: "1024", ID: id}), "size", repository.ValidImageSortValue, true},
+		{"bad id", encodeCursor(pageCursor{Sort: "size", Order: "desc", Value: "1024", ID: "1 OR 1=1"}), "size", repository.ValidImageSortValue, true},
+		{"size not a number", encodeCursor(pageCursor{Sort: "size", Order: "desc", Value: "big", ID: id}), "size", repository.ValidImageSortValue, true},
+		{"uploaded not a time", encodeCursor(pageCursor{Sort: "uploaded", Order: "desc", Value: "yesterday", ID: id}), "uploaded", repository.ValidImageSortValue, true},
+		{"captured with zone", encodeCursor(pageCursor{Sort: "captured", Order: "desc", Value: created, ID: id}), "captured", repository.ValidImageSortValue, true},
+		{"folder created not a time", encodeCursor(pageCursor{Sort: "created", Order: "desc", Value: "1024", ID: id}), "created", repository.ValidFolderSortValue, true},
+		{"folder name with NUL", encodeCursor(pageCursor{Sort: "name", Order: "desc", Value: "a\x00b", ID: id}), "name", repository.ValidFolderSortValue, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.raw, tt.sort, "desc", tt.valid)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
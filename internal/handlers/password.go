@@ -1,14 +1,17 @@
 package handlers
 
 import (
-	"Backend/internal/db"
+	"Backend/internal/apierror"
+	"Backend/internal/mail"
 	"Backend/internal/middleware"
+	"Backend/internal/repository"
 	"Backend/internal/utils"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type ForgotPasswordRequest struct {
@@ -24,50 +27,62 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"newPassword"`
 }
 
-func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, apierror.Unauthorized, "Unauthorized")
 		return
 	}
 
 	var req ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, apierror.BadRequest, "Invalid request body")
 		return
 	}
 
 	if req.CurrentPassword == "" || req.NewPassword == "" {
-		respondWithError(w, http.StatusBadRequest, "Both current and new passwords are required")
+		respondWithValidation(w, "Both current and new passwords are required", missing("currentPassword", req.CurrentPassword, "newPassword", req.NewPassword)...)
 		return
 	}
 
 	// Fetch current hashed password
-	var hashedPassword string
-	err := db.DB.QueryRow(`SELECT password FROM users WHERE id = $1`, userID).Scan(&hashedPassword)
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "User not found")
+		respondWithUserLookupError(w, err)
 		return
 	}
 
 	// Compare current password
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.CurrentPassword))
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+		respondWithError(w, apierror.InvalidCredentials, "Current password is incorrect")
 		return
 	}
 
 	// Hash new password
 	newHashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to hash new password")
+		respondWithError(w, apierror.Internal, "Failed to hash new password")
 		return
 	}
 
 	// Update DB
-	_, err = db.DB.Exec(`UPDATE users SET password = $1 WHERE id = $2`, string(newHashed), userID)
+	err = h.repos.Users.UpdatePassword(r.Context(), userID, string(newHashed))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		respondWithError(w, apierror.Internal, "Failed to update password")
+		return
+	}
+
+	// Reset links sent before the change must not be able to undo it
+	if err := h.repos.PasswordResets.InvalidateAll(r.Context(), userID); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to update password")
+		return
+	}
+
+	// Sign out every other device; the session making this request stays valid
+	sessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+	if err := h.repos.Sessions.RevokeAll(r.Context(), userID, sessionID); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to revoke sessions")
 		return
 	}
 
@@ -76,80 +91,104 @@ func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	var req ForgotPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		respondWithError(w, apierror.BadRequest, "Invalid JSON body")
 		return
 	}
 
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 	if email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required")
+		respondWithValidation(w, "Email is required", apierror.Required("email"))
 		return
 	}
 
 	// Default response message
 	message := "If the email exists, a password reset link has been sent."
 	response := map[string]string{
-		"message":   message,
-		"resetLink": "", // Default empty
+		"message": message,
 	}
 
 	// Try to find user
-	var userID string
-	err := db.DB.QueryRow(`SELECT id FROM users WHERE email = $1`, email).Scan(&userID)
+	user, err := h.repos.Users.GetByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		middleware.Logger(r.Context()).Error("look up user for password reset", "error", err)
+	}
 	// Send email if user found
-	if err == nil {
-		token, tokenErr := utils.GenerateResetToken(userID)
+	if err == nil && user.EmailVerifiedAt == nil {
+		// Reset links only go to proven addresses; ask for proof instead
+		_ = h.sendVerificationEmail(r, user)
+	} else if err == nil {
+		// Only the hash is stored, so a leaked table can't be used to reset passwords
+		token, tokenErr := utils.GenerateOpaqueToken()
 		if tokenErr == nil {
-			resetLink := fmt.Sprintf("http://localhost:3000/reset-password?token=%s", token)
-			_ = utils.SendResetEmail(email, resetLink) // send reset email
-			response["resetLink"] = resetLink          // for testing, can be removed later
+			tokenErr = h.repos.PasswordResets.Create(r.Context(), user.ID, utils.HashToken(token), time.Now().Add(utils.PasswordResetTTL))
+		}
+		if tokenErr != nil {
+			middleware.Logger(r.Context()).Error("create password reset token", "user_id", user.ID, "error", tokenErr)
+		} else {
+			resetLink := h.links.ResetPassword(token)
+			// send reset email
+			err := h.mailer.Send(r.Context(), email, mail.Reset, r.Header.Get("Accept-Language"), mail.ResetData{
+				Name:             user.FirstName,
+				Link:             resetLink,
+				ExpiresInMinutes: int(utils.PasswordResetTTL.Minutes()),
+			})
+			if err != nil {
+				middleware.Logger(r.Context()).Error("send password reset email", "user_id", user.ID, "error", err)
+			}
+			if h.devMode {
+				// Lets the reset flow be tested without a mailbox
+				response["resetLink"] = resetLink
+			}
 		}
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	// Get token from URL param
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		respondWithError(w, http.StatusBadRequest, "Reset token is required in the query parameters")
+		respondWithValidation(w, "Reset token is required in the query parameters", apierror.Required("token"))
 		return
 	}
 
 	// Parse JSON body
 	var req ResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		respondWithError(w, apierror.BadRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.NewPassword == "" {
-		respondWithError(w, http.StatusBadRequest, "New password is required")
+		respondWithValidation(w, "New password is required", apierror.Required("newPassword"))
 		return
 	}
 
-	// Parse and verify token
-	userID, err := utils.ParseResetToken(token)
+	// Hash the new password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid or expired reset token")
+		respondWithError(w, apierror.Internal, "Failed to hash password")
 		return
 	}
 
-	// Hash the new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	// Use up the token and update the password together
+	userID, err := h.repos.PasswordResets.Redeem(r.Context(), utils.HashToken(token), string(hashedPassword))
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.InvalidToken, "Invalid or expired reset token")
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		respondWithError(w, apierror.Internal, "Failed to update password")
 		return
 	}
 
-	// Update password in the DB
-	_, err = db.DB.Exec(`UPDATE users SET password = $1 WHERE id = $2`, string(hashedPassword), userID)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+	// Whoever held the old password must not stay signed in
+	if err := h.repos.Sessions.RevokeAll(r.Context(), userID, ""); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to revoke sessions")
 		return
 	}
 
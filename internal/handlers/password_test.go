@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/handlers"
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// requestReset asks for a password reset and returns the token from the
+// reset link, which DevMode includes in the response.
+func (ts *testServer) requestReset(email string) string {
+	ts.t.Helper()
+
+	var resp map[string]string
+	status := ts.doJSON(http.MethodPost, "/api/forgotPassword", "", handlers.ForgotPasswordRequest{Email: email}, &resp)
+	if status != http.StatusOK {
+		ts.t.Fatalf("forgot password: status %d", status)
+	}
+	link, err := url.Parse(resp["resetLink"])
+	if err != nil || link.Query().Get("token") == "" {
+		ts.t.Fatalf("forgot password: no reset link in %v", resp)
+	}
+	return link.Query().Get("token")
+}
+
+func TestResetTokenIsSingleUse(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	login := ts.signUp("ada@example.com", "old-secret")
+	if _, err := ts.repos.Users.MarkEmailVerified(context.Background(), login.UserID, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	token := ts.requestReset("ada@example.com")
+	path := "/api/resetPassword?token=" + url.QueryEscape(token)
+	if status := ts.doJSON(http.MethodPost, path, "", handlers.ResetPasswordRequest{NewPassword: "new-secret"}, nil); status != http.StatusOK {
+		t.Fatalf("reset: status %d", status)
+	}
+
+	var apiErr apierror.Error
+	status := ts.doJSON(http.MethodPost, path, "", handlers.ResetPasswordRequest{NewPassword: "another-secret"}, &apiErr)
+	if status != http.StatusUnauthorized || apiErr.Code != apierror.InvalidToken {
+		t.Fatalf("second reset with the same token: status %d, code %q", status, apiErr.Code)
+	}
+
+	// The first reset stands and signed out the old session
+	ts.login("ada@example.com", "new-secret")
+	if status := ts.do(http.MethodGet, "/api/getUserData", login.Token, "", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("session from before the reset: status %d", status)
+	}
+}
+
+func TestResetInvalidatesOtherTokens(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	login := ts.signUp("ada@example.com", "old-secret")
+	if _, err := ts.repos.Users.MarkEmailVerified(context.Background(), login.UserID, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	first := ts.requestReset("ada@example.com")
+	second := ts.requestReset("ada@example.com")
+
+	status := ts.doJSON(http.MethodPost, "/api/resetPassword?token="+url.QueryEscape(second), "", handlers.ResetPasswordRequest{NewPassword: "new-secret"}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("reset: status %d", status)
+	}
+	status = ts.doJSON(http.MethodPost, "/api/resetPassword?token="+url.QueryEscape(first), "", handlers.ResetPasswordRequest{NewPassword: "stolen-secret"}, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("reset with another outstanding token: status %d", status)
+	}
+}
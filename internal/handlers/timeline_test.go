@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"Backend/internal/handlers"
+	"Backend/internal/repository"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimelineGroupsByCaptureDay(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+
+	captured := []time.Time{
+		time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC),
+		time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 3, 7, 15, 0, 0, time.UTC),
+		time.Date(2024, 6, 3, 23, 59, 0, 0, time.UTC),
+	}
+	for _, c := range captured {
+		if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/x", CapturedAt: &c}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Uploaded now without a capture time, so it sorts after every photo
+	if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/y"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	// Walk the timeline oldest first, two images a page, merging a day that
+	// continues onto the next page as clients do.
+	var days []handlers.TimelineDay
+	path := "/api/images/timeline?order=asc&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("timeline did not end")
+		}
+		var page handlers.Page[handlers.TimelineDay]
+		if status := ts.do(http.MethodGet, path, owner.Token, "", nil, &page); status != http.StatusOK {
+			t.Fatalf("GET %s: status %d", path, status)
+		}
+		for i, day := range page.Items {
+			if day.Count != len(day.Images) {
+				t.Errorf("%s: count %d, %d images", day.Date, day.Count, len(day.Images))
+			}
+			if i == 0 && len(days) > 0 && days[len(days)-1].Date == day.Date {
+				last := &days[len(days)-1]
+				last.Count += day.Count
+				last.Images = append(last.Images, day.Images...)
+				continue
+			}
+			days = append(days, day)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/api/images/timeline?order=asc&limit=2&cursor=" + page.NextCursor
+	}
+
+	want := []struct {
+		date  string
+		count int
+	}{
+		{"2024-06-01", 2},
+		{"2024-06-02", 1},
+		{"2024-06-03", 2},
+		{today, 1},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("got %d days, want %d: %+v", len(days), len(want), days)
+	}
+	for i, w := range want {
+		if days[i].Date != w.date || days[i].Count != w.count {
+			t.Errorf("day %d = %s (%d), want %s (%d)", i, days[i].Date, days[i].Count, w.date, w.count)
+		}
+	}
+	if first := days[0].Images[0]; first.CapturedAt == nil || !first.CapturedAt.Equal(captured[0]) {
+		t.Errorf("first image captured at %v, want %v", first.CapturedAt, captured[0])
+	}
+}
+
+func TestTimelineIsOwnerScoped(t *testing.T) {
+	ts := newTestServer(t, "", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "correct horse battery")
+	other := ts.signUp("other@example.com", "correct horse battery")
+
+	if _, err := ts.repos.Images.Create(context.Background(), repository.Image{UserID: owner.UserID, URL: "http://files.test/x"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var page handlers.Page[handlers.TimelineDay]
+	if status := ts.do(http.MethodGet, "/api/images/timeline", other.Token, "", nil, &page); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("other user sees %d days", len(page.Items))
+	}
+}
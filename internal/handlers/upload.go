@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/imaging"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"Backend/internal/storage"
+	"Backend/internal/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// uploadTarget is where the files of one upload request are filed.
+type uploadTarget struct {
+	UserID     string
+	FolderID   *string
+	LinkID     string
+	DeviceInfo string
+}
+
+// UploadLimits bounds what guests may upload.
+type UploadLimits struct {
+	// MaxFileBytes is the largest single file accepted; 0 means no limit.
+	MaxFileBytes int64
+	// MaxRequestBytes caps the whole body of one upload request; 0 means no
+	// limit.
+	MaxRequestBytes int64
+	// Concurrency is how many files the server processes at once, across all
+	// upload requests; twice as many images may be held in memory, each up
+	// to 32 MiB. It defaults to DefaultUploadConcurrency.
+	Concurrency int
+}
+
+// DefaultUploadConcurrency is the number of upload slots used when the
+// configuration doesn't set one.
+const DefaultUploadConcurrency = 4
+
+const (
+	// maxDecodeBytes is the largest image kept in memory to generate
+	// variants from; larger images are stored as the original only.
+	maxDecodeBytes = 32 << 20
+	// maxFieldBytes bounds each text field of an upload request.
+	maxFieldBytes = 64 << 10
+)
+
+var errFileTooLarge = errors.New("file exceeds the size limit")
+
+// fileError explains why one file of an upload request was not stored. Err is
+// logged; only Code and Message are sent to the uploader.
+type fileError struct {
+	Code    apierror.Code
+	Message string
+	Err     error
+}
+
+func (e *fileError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *fileError) Unwrap() error { return e.Err }
+
+func storageFailed(err error) *fileError {
+	return &fileError{Code: apierror.UploadStorageFailed, Message: "The storage service failed, try again", Err: err}
+}
+
+func saveFailed(err error) *fileError {
+	return &fileError{Code: apierror.UploadSaveFailed, Message: "Failed to save the file, try again", Err: err}
+}
+
+// cancelled reports a file abandoned because the client disconnected.
+func cancelled(err error) *fileError {
+	return &fileError{Code: apierror.UploadCancelled, Message: "The upload was cancelled", Err: err}
+}
+
+func quotaExceeded() *fileError {
+	return &fileError{Code: apierror.UploadQuotaExceeded, Message: "The upload link's file or size limit has been reached"}
+}
+
+// uploadResult is the outcome of one file of an upload request.
+type uploadResult struct {
+	name string
+	file SuccessfulFile
+	err  *fileError
+}
+
+// receivedFile is an original that has been streamed into storage and counted
+// against its upload link, but not yet recorded.
+type receivedFile struct {
+	name        string
+	contentType string
+	obj         storage.Object
+	size        int64
+	// data holds the file when it is an image small enough to decode
+	data []byte
+}
+
+// countingReader counts the bytes read through it and fails with
+// errFileTooLarge once more than limit have been read. It remembers the first
+// error from the underlying reader, since storage backends may wrap it.
+type countingReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+	err   error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.limit > 0 && c.n > c.limit {
+		err = errFileTooLarge
+	}
+	if err != nil && err != io.EOF && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// decodeBuffer keeps a copy of a streamed image for decoding, giving up once
+// it outgrows maxDecodeBytes.
+type decodeBuffer struct {
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (b *decodeBuffer) Write(p []byte) (int, error) {
+	if !b.overflow {
+		if b.buf.Len()+len(p) > maxDecodeBytes {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// bytes returns the buffered file, or nil if it was too large to keep.
+func (b *decodeBuffer) bytes() []byte {
+	if b == nil || b.overflow {
+		return nil
+	}
+	return b.buf.Bytes()
+}
+
+// fencedReader guards a request part handed to a storage backend. Some
+// backends keep reading in a goroutine of their own after Put returns, so
+// the part may only be inspected or advanced past once fence has returned.
+type fencedReader struct {
+	mu     sync.Mutex
+	r      io.Reader
+	fenced bool
+}
+
+var errFenced = errors.New("upload stream already closed")
+
+func (f *fencedReader) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fenced {
+		return 0, errFenced
+	}
+	return f.r.Read(p)
+}
+
+// fence waits for any read in progress and fails all later ones.
+func (f *fencedReader) fence() {
+	f.mu.Lock()
+	f.fenced = true
+	f.mu.Unlock()
+}
+
+// receiveUpload streams one file part straight into storage and counts it
+// against the upload link. Its type is sniffed from the first bytes and its
+// size is enforced while it is read, so nothing is spooled to disk and only
+// images small enough to decode are kept in memory. Such an image holds one
+// of the handler's upload buffers until finishUpload is done with it.
+func (h *Handler) receiveUpload(ctx context.Context, target uploadTarget, name string, part io.Reader) (rf receivedFile, ferr *fileError) {
+	br := bufio.NewReaderSize(part, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return receivedFile{}, readFailed(ctx, err)
+	}
+
+	// Trust the file's contents, not the name or type the client claimed
+	contentType := imaging.DetectContentType(head)
+	if !imaging.IsMedia(contentType) {
+		return receivedFile{}, &fileError{
+			Code:    apierror.UploadUnsupportedType,
+			Message: fmt.Sprintf("Files of type %s are not supported, only images and videos", contentType),
+		}
+	}
+
+	// Images are kept in memory until their variants are made, so bound how
+	// many are held across all requests before buffering this one
+	var dbuf *decodeBuffer
+	if imaging.Decodable(contentType) {
+		select {
+		case h.uploadBuffers <- struct{}{}:
+		case <-ctx.Done():
+			return receivedFile{}, cancelled(ctx.Err())
+		}
+		defer func() {
+			// The buffer is only kept for an image that was received whole
+			if rf.data == nil {
+				<-h.uploadBuffers
+			}
+		}()
+		dbuf = &decodeBuffer{}
+	}
+
+	// Claim a file slot on the link now; the bytes are counted once known
+	if err := h.repos.UploadLinks.Reserve(ctx, target.LinkID, 0); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return receivedFile{}, quotaExceeded()
+		}
+		if ctx.Err() != nil {
+			return receivedFile{}, cancelled(err)
+		}
+		return receivedFile{}, saveFailed(err)
+	}
+
+	var (
+		stored string
+		size   int64
+	)
+	fail := func(ferr *fileError) (receivedFile, *fileError) {
+		// Clean up even if the request was cancelled
+		ctx := context.WithoutCancel(ctx)
+		if stored != "" {
+			_ = h.cleaner.DeleteAsset(ctx, stored)
+		}
+		if err := h.repos.UploadLinks.Release(ctx, target.LinkID, size); err != nil {
+			middleware.Logger(ctx).Error("release upload link reservation", "upload_link_id", target.LinkID, "error", err)
+		}
+		return receivedFile{}, ferr
+	}
+
+	src := &countingReader{r: br, limit: h.uploads.MaxFileBytes}
+	var body io.Reader = src
+	if dbuf != nil {
+		body = io.TeeReader(src, dbuf)
+	}
+	guarded := &fencedReader{r: body}
+
+	key := fmt.Sprintf("images/%s/%s%s", target.UserID, uuid.New().String(), imaging.Extension(contentType))
+	obj, err := h.store.Put(ctx, key, guarded, contentType)
+	// Nothing below may race with a backend still reading the part
+	guarded.fence()
+	if err == nil {
+		stored = obj.Key
+	}
+	// A failed read is the client's doing, whatever the backend made of it
+	if src.err != nil {
+		if errors.Is(src.err, errFileTooLarge) {
+			return fail(&fileError{
+				Code:    apierror.UploadTooLarge,
+				Message: fmt.Sprintf("File is larger than the %d byte limit", h.uploads.MaxFileBytes),
+			})
+		}
+		return fail(readFailed(ctx, src.err))
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return fail(cancelled(err))
+		}
+		return fail(storageFailed(err))
+	}
+
+	if err := h.repos.UploadLinks.AddBytes(ctx, target.LinkID, src.n); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return fail(quotaExceeded())
+		}
+		if ctx.Err() != nil {
+			return fail(cancelled(err))
+		}
+		return fail(saveFailed(err))
+	}
+	size = src.n
+
+	return receivedFile{
+		name:        name,
+		contentType: contentType,
+		obj:         obj,
+		size:        size,
+		data:        dbuf.bytes(),
+	}, nil
+}
+
+// readFailed classifies an error reading an uploaded file from the request.
+func readFailed(ctx context.Context, err error) *fileError {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return &fileError{
+			Code:    apierror.UploadTooLarge,
+			Message: fmt.Sprintf("The request is larger than the %d byte limit; later files were not received", maxErr.Limit),
+			Err:     err,
+		}
+	case ctx.Err() != nil:
+		return cancelled(err)
+	}
+	return &fileError{Code: apierror.UploadReadFailed, Message: "File could not be read", Err: err}
+}
+
+// finishUpload saves a received file once one of the handler's upload slots
+// is free, so at most that many files are decoded and recorded at once across
+// all requests. It gives back the file's upload buffer, if it holds one.
+func (h *Handler) finishUpload(ctx context.Context, target uploadTarget, rf receivedFile) (SuccessfulFile, *fileError) {
+	if rf.data != nil {
+		defer func() { <-h.uploadBuffers }()
+	}
+	select {
+	case h.uploadSlots <- struct{}{}:
+		defer func() { <-h.uploadSlots }()
+	case <-ctx.Done():
+		h.discardUpload(ctx, target, rf)
+		return SuccessfulFile{}, cancelled(ctx.Err())
+	}
+	return h.saveUpload(ctx, target, rf)
+}
+
+// discardUpload removes the given assets of a received file and gives its
+// reservation on the upload link back, even if the request was cancelled.
+func (h *Handler) discardUpload(ctx context.Context, target uploadTarget, rf receivedFile, keys ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range append([]string{rf.obj.Key}, keys...) {
+		_ = h.cleaner.DeleteAsset(ctx, key)
+	}
+	if err := h.repos.UploadLinks.Release(ctx, target.LinkID, rf.size); err != nil {
+		middleware.Logger(ctx).Error("release upload link reservation", "upload_link_id", target.LinkID, "error", err)
+	}
+}
+
+// saveUpload generates the variants of a received file and records it in the
+// database. On any failure, its assets are removed and its reservation on the
+// upload link is given back.
+func (h *Handler) saveUpload(ctx context.Context, target uploadTarget, rf receivedFile) (SuccessfulFile, *fileError) {
+	var stored []string
+	fail := func(err *fileError) (SuccessfulFile, *fileError) {
+		h.discardUpload(ctx, target, rf, stored...)
+		return SuccessfulFile{}, err
+	}
+
+	base := strings.TrimSuffix(rf.obj.Key, path.Ext(rf.obj.Key))
+	size := rf.size
+	meta, _ := imaging.ReadMetadata(rf.data)
+	image := repository.Image{
+		UserID:       target.UserID,
+		URL:          rf.obj.URL,
+		StorageKey:   rf.obj.Key,
+		DeviceInfo:   json.RawMessage(target.DeviceInfo),
+		FolderID:     target.FolderID,
+		SizeBytes:    &size,
+		CapturedAt:   meta.CapturedAt,
+		CameraMake:   optionalString(meta.CameraMake),
+		CameraModel:  optionalString(meta.CameraModel),
+		Orientation:  optionalInt(meta.Orientation),
+		GPSLatitude:  meta.Latitude,
+		GPSLongitude: meta.Longitude,
+	}
+	if target.LinkID != "" {
+		image.UploadLinkID = &target.LinkID
+	}
+
+	// Generate smaller renditions for images we kept and can decode; other
+	// files (e.g. videos) are kept as the original only
+	var variants []repository.ImageVariant
+	if img, _, err := imaging.Decode(rf.data); err == nil {
+		// Variants carry no EXIF, so bake the orientation into their pixels
+		img = imaging.ApplyOrientation(img, meta.Orientation)
+		b := img.Bounds()
+		width, height := b.Dx(), b.Dy()
+		image.Width, image.Height = &width, &height
+
+		generated, err := imaging.Generate(img)
+		if err != nil {
+			return fail(saveFailed(err))
+		}
+		for _, v := range generated {
+			vobj, err := h.store.Put(ctx, fmt.Sprintf("%s_%s.jpg", base, v.Name), bytes.NewReader(v.Data), "image/jpeg")
+			if err != nil {
+				if ctx.Err() != nil {
+					return fail(cancelled(err))
+				}
+				return fail(storageFailed(err))
+			}
+			stored = append(stored, vobj.Key)
+
+			vw, vh := v.Width, v.Height
+			variants = append(variants, repository.ImageVariant{
+				Name:       v.Name,
+				StorageKey: vobj.Key,
+				URL:        vobj.URL,
+				Width:      &vw,
+				Height:     &vh,
+				SizeBytes:  int64(len(v.Data)),
+			})
+		}
+	}
+
+	// Token that lets the uploader delete this image without an account
+	deletionToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return fail(saveFailed(err))
+	}
+	image.DeletionTokenHash = utils.HashToken(deletionToken)
+
+	image, err = h.repos.Images.Create(ctx, image, variants)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fail(cancelled(err))
+		}
+		return fail(saveFailed(err))
+	}
+
+	return SuccessfulFile{
+		ID:            image.ID,
+		UserID:        target.UserID,
+		Link:          rf.obj.URL,
+		Name:          rf.name,
+		DeletionToken: deletionToken,
+		Variants:      image.Variants,
+	}, nil
+}
+
+// cleanFileName makes a client-supplied file name safe to echo back and log:
+// directories and control characters are dropped and the length is capped.
+// It is never used to build storage keys.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalInt(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"Backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateUploadLinkRequest struct {
+	Name     string     `json:"name"`
+	FolderID string     `json:"folderId"`
+	StartsAt *time.Time `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt"`
+	MaxFiles *int64     `json:"maxFiles"`
+	MaxBytes *int64     `json:"maxBytes"`
+}
+
+type CreateUploadLinkResponse struct {
+	repository.UploadLink
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// PublicUploadLink is what a guest holding the token is allowed to see.
+type PublicUploadLink struct {
+	Name           string     `json:"name"`
+	EndsAt         *time.Time `json:"ends_at"`
+	FilesRemaining *int64     `json:"files_remaining"`
+	BytesRemaining *int64     `json:"bytes_remaining"`
+}
+
+// createUploadLink stores a new link for userID with the given token.
+func (h *Handler) createUploadLink(ctx context.Context, userID, token string, req CreateUploadLinkRequest) (repository.UploadLink, error) {
+	link := repository.UploadLink{
+		UserID:    userID,
+		Name:      req.Name,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		MaxFiles:  req.MaxFiles,
+		MaxBytes:  req.MaxBytes,
+		TokenHash: utils.HashToken(token),
+	}
+	if req.FolderID != "" {
+		link.FolderID = &req.FolderID
+	}
+	return h.repos.UploadLinks.Create(ctx, link)
+}
+
+// activeUploadLink resolves a token to a link that is neither revoked nor
+// outside its time window. It returns repository.ErrNotFound otherwise.
+func (h *Handler) activeUploadLink(ctx context.Context, token string) (repository.UploadLink, error) {
+	return h.repos.UploadLinks.GetActive(ctx, utils.HashToken(token))
+}
+
+// POST /upload-links
+func (h *Handler) CreateUploadLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	if h.verification.Sharing && !h.requireVerifiedEmail(w, r, userId) {
+		return
+	}
+
+	var req CreateUploadLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, apierror.BadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Name) > 255 {
+		respondWithValidation(w, "Link name must be less than 255 characters", apierror.TooLong("name", 255))
+		return
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		respondWithValidation(w, "endsAt must be after startsAt", apierror.BadField("endsAt", "must be after startsAt"))
+		return
+	}
+	var limitErrs []apierror.FieldError
+	if req.MaxFiles != nil && *req.MaxFiles <= 0 {
+		limitErrs = append(limitErrs, apierror.BadField("maxFiles", "must be positive"))
+	}
+	if req.MaxBytes != nil && *req.MaxBytes <= 0 {
+		limitErrs = append(limitErrs, apierror.BadField("maxBytes", "must be positive"))
+	}
+	if len(limitErrs) > 0 {
+		respondWithValidation(w, "maxFiles and maxBytes must be positive", limitErrs...)
+		return
+	}
+	if req.FolderID != "" {
+		owned, err := h.repos.Folders.BelongsTo(r.Context(), req.FolderID, userId)
+		if err != nil {
+			respondWithError(w, apierror.Internal, "Database error")
+			return
+		}
+		if !owned {
+			respondWithError(w, apierror.FolderNotFound, "Folder not found or access denied")
+			return
+		}
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to generate upload link")
+		return
+	}
+
+	link, err := h.createUploadLink(r.Context(), userId, token, req)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to create upload link")
+		return
+	}
+
+	// The token is only ever returned here; only its hash is stored
+	respondWithJSON(w, http.StatusCreated, CreateUploadLinkResponse{
+		UploadLink: link,
+		Token:      token,
+		URL:        h.links.Upload(token),
+	})
+}
+
+// GET /upload-links
+func (h *Handler) GetUploadLinksHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	links, err := h.repos.UploadLinks.ListByUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Database query error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, links)
+}
+
+// DELETE /upload-links/{id}
+func (h *Handler) RevokeUploadLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userId == "" {
+		respondWithError(w, apierror.Unauthorized, "Invalid user or not logged in")
+		return
+	}
+
+	linkID := chi.URLParam(r, "id")
+	err := h.repos.UploadLinks.Revoke(r.Context(), linkID, userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.UploadLinkNotFound, "Upload link not found or access denied")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to revoke upload link")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Upload link revoked successfully",
+	})
+}
+
+// GET /upload/link?token=...
+// Lets the guest upload page show what the link allows before uploading.
+func (h *Handler) GetPublicUploadLinkHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithValidation(w, "Upload token is required", apierror.Required("token"))
+		return
+	}
+
+	link, err := h.activeUploadLink(r.Context(), token)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.UploadLinkInvalid, "Upload link is invalid, expired or revoked")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Database error")
+		return
+	}
+
+	resp := PublicUploadLink{Name: link.Name, EndsAt: link.EndsAt}
+	if link.MaxFiles != nil {
+		remaining := max(*link.MaxFiles-link.FilesUploaded, 0)
+		resp.FilesRemaining = &remaining
+	}
+	if link.MaxBytes != nil {
+		remaining := max(*link.MaxBytes-link.BytesUploaded, 0)
+		resp.BytesRemaining = &remaining
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/handlers"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestUploadLinkFileLimit(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	maxFiles := int64(2)
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party", MaxFiles: &maxFiles})
+
+	status, resp := ts.upload(uploadToken, pngFile(t, "a.png"), pngFile(t, "b.png"), pngFile(t, "c.png"))
+	if status != http.StatusMultiStatus || len(resp.Successful) != 2 || len(resp.Failed) != 1 {
+		t.Fatalf("status %d, response %+v", status, resp)
+	}
+	if resp.Failed[0].Code != apierror.UploadQuotaExceeded {
+		t.Fatalf("failure code = %q", resp.Failed[0].Code)
+	}
+
+	var link handlers.PublicUploadLink
+	if status := ts.do(http.MethodGet, "/api/upload/link?token="+url.QueryEscape(uploadToken), "", "", nil, &link); status != http.StatusOK {
+		t.Fatalf("get link: status %d", status)
+	}
+	if link.FilesRemaining == nil || *link.FilesRemaining != 0 {
+		t.Fatalf("files remaining = %v, want 0", link.FilesRemaining)
+	}
+
+	// The link is used up, so nothing more is stored
+	status, resp = ts.upload(uploadToken, pngFile(t, "d.png"))
+	if status != http.StatusBadRequest || len(resp.Failed) != 1 || resp.Failed[0].Code != apierror.UploadQuotaExceeded {
+		t.Fatalf("upload to a full link: status %d, response %+v", status, resp)
+	}
+}
+
+func TestUploadLinkByteLimit(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	file := pngFile(t, "a.png")
+	maxBytes := int64(len(file[1]) + len(file[1])/2)
+	uploadToken := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "party", MaxBytes: &maxBytes})
+
+	status, resp := ts.upload(uploadToken, file, file)
+	if status != http.StatusMultiStatus || len(resp.Failed) != 1 || resp.Failed[0].Code != apierror.UploadQuotaExceeded {
+		t.Fatalf("status %d, response %+v", status, resp)
+	}
+
+	// The rejected file's bytes were given back
+	var link handlers.PublicUploadLink
+	ts.do(http.MethodGet, "/api/upload/link?token="+url.QueryEscape(uploadToken), "", "", nil, &link)
+	if link.BytesRemaining == nil || *link.BytesRemaining != maxBytes-int64(len(file[1])) {
+		t.Fatalf("bytes remaining = %v, want %d", link.BytesRemaining, maxBytes-int64(len(file[1])))
+	}
+}
+
+func TestUploadLinkRejectsInactiveLinks(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+
+	var revoked handlers.CreateUploadLinkResponse
+	if status := ts.doJSON(http.MethodPost, "/api/upload-links", owner.Token, handlers.CreateUploadLinkRequest{Name: "revoked"}, &revoked); status != http.StatusCreated {
+		t.Fatalf("create upload link: status %d", status)
+	}
+	if status := ts.do(http.MethodDelete, "/api/upload-links/"+revoked.ID, owner.Token, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("revoke upload link: status %d", status)
+	}
+
+	start, end := time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)
+	expired := ts.createUploadLink(owner.Token, handlers.CreateUploadLinkRequest{Name: "expired", StartsAt: &start, EndsAt: &end})
+
+	for name, token := range map[string]string{"revoked": revoked.Token, "expired": expired, "unknown": "no-such-token"} {
+		var apiErr apierror.Error
+		status := ts.do(http.MethodGet, "/api/upload/link?token="+url.QueryEscape(token), "", "", nil, &apiErr)
+		if status != http.StatusForbidden || apiErr.Code != apierror.UploadLinkInvalid {
+			t.Errorf("%s link: status %d, code %q", name, status, apiErr.Code)
+		}
+
+		ct, body := form(t, [][2]string{{"uploadToken", token}, {"deviceInfo", testDeviceInfo}}, pngFile(t, "a.png"))
+		status = ts.do(http.MethodPost, "/api/upload/files", "", ct, body, &apiErr)
+		if status != http.StatusForbidden || apiErr.Code != apierror.UploadLinkInvalid {
+			t.Errorf("upload to %s link: status %d, code %q", name, status, apiErr.Code)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"Backend/internal/handlers"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestDeleteUserPurgesEverything(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	other := ts.signUp("other@example.com", "secret")
+	folder := ts.createFolder(owner.Token, "Holiday")
+	a, b := ts.uploadOne(owner.Token), ts.uploadOne(owner.Token)
+	kept := ts.uploadOne(other.Token)
+	req := handlers.MoveImagesRequest{ImageIDs: []string{a.ID}, FolderID: folder}
+	if status := ts.doJSON(http.MethodPost, "/api/images/copy", owner.Token, req, nil); status != http.StatusOK {
+		t.Fatalf("copy: status %d", status)
+	}
+
+	var resp handlers.DeleteUserResponse
+	if status := ts.do(http.MethodDelete, "/api/deleteUser", owner.Token, "", nil, &resp); status != http.StatusOK {
+		t.Fatalf("delete user: status %d", status)
+	}
+	report := resp.Report
+	if report.ImagesDeleted != 3 || report.FoldersDeleted != 1 || !report.QRCodeDeleted || report.AssetsQueued != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	// Every original and variant goes once, however many copies shared it,
+	// plus the QR code
+	if deleted := len(ts.deletedKeys()); deleted != report.AssetsDeleted+1 || report.AssetsDeleted < 2 {
+		t.Fatalf("deleted %d keys, report says %d assets and the QR code", deleted, report.AssetsDeleted)
+	}
+
+	if status := ts.do(http.MethodGet, "/api/getUserData", owner.Token, "", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("session of the deleted user: status %d", status)
+	}
+	ct, body := form(t, [][2]string{{"email", "owner@example.com"}, {"password", "secret"}})
+	if status := ts.do(http.MethodPost, "/api/login", "", ct, body, nil); status != http.StatusUnauthorized {
+		t.Fatalf("login as the deleted user: status %d", status)
+	}
+	for _, img := range []handlers.SuccessfulFile{a, b} {
+		if status := ts.do(http.MethodDelete, "/api/upload/files/"+img.ID+"?token="+img.DeletionToken, "", "", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("image of the deleted user still exists: status %d", status)
+		}
+	}
+
+	// Other accounts are untouched
+	page := ts.listImages(other.Token, "/api/images")
+	if len(page.Items) != 1 || page.Items[0].ID != kept.ID {
+		t.Fatalf("other user's images = %+v", page.Items)
+	}
+}
+
+func TestDeleteUserQueuesFailedAssetDeletions(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	owner := ts.signUp("owner@example.com", "secret")
+	ts.uploadOne(owner.Token)
+
+	ts.failDeletes.Store(true)
+	var resp handlers.DeleteUserResponse
+	if status := ts.do(http.MethodDelete, "/api/deleteUser", owner.Token, "", nil, &resp); status != http.StatusOK {
+		t.Fatalf("delete user: status %d", status)
+	}
+	if resp.Report.AssetsDeleted != 0 || resp.Report.QRCodeDeleted || resp.Report.AssetsQueued < 3 {
+		t.Fatalf("report = %+v, want every asset queued", resp.Report)
+	}
+
+	pending, err := ts.repos.Assets.PendingDeletions(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != resp.Report.AssetsQueued {
+		t.Fatalf("%d deletions pending, report says %d were queued", len(pending), resp.Report.AssetsQueued)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/repository"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespondWithUserLookupError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   apierror.Code
+	}{
+		{repository.ErrNotFound, http.StatusNotFound, apierror.UserNotFound},
+		{errors.New("connection refused"), http.StatusInternalServerError, apierror.Internal},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		respondWithUserLookupError(rec, tt.err)
+
+		var body apierror.Error
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.wantStatus || body.Code != tt.wantCode {
+			t.Errorf("%v: status %d, code %q; want %d, %q", tt.err, rec.Code, body.Code, tt.wantStatus, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"Backend/internal/apierror"
+	"Backend/internal/mail"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
+	"Backend/internal/storage"
+	"Backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// VerificationPolicy lists the features held back until a user has verified
+// their email address.
+type VerificationPolicy struct {
+	// QRCode defers generating the account's QR code and default upload link.
+	QRCode bool
+	// Sharing blocks creating upload links for guests.
+	Sharing bool
+}
+
+// ParseVerificationPolicy reads a comma-separated feature list such as
+// "qrcode,sharing". An empty string restricts every feature; "none" restricts
+// nothing.
+func ParseVerificationPolicy(raw string) (VerificationPolicy, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return VerificationPolicy{QRCode: true, Sharing: true}, nil
+	}
+
+	var p VerificationPolicy
+	for _, feature := range strings.Split(raw, ",") {
+		switch strings.ToLower(strings.TrimSpace(feature)) {
+		case "none", "":
+		case "qrcode":
+			p.QRCode = true
+		case "sharing":
+			p.Sharing = true
+		default:
+			return p, fmt.Errorf("unknown feature %q, expected qrcode, sharing or none", feature)
+		}
+	}
+	return p, nil
+}
+
+// sendVerificationEmail mails user a fresh verification link in the
+// language the request asked for.
+func (h *Handler) sendVerificationEmail(r *http.Request, user repository.User) error {
+	token, err := utils.GenerateEmailVerificationToken(h.jwtSecret, user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	err = h.mailer.Send(r.Context(), user.Email, mail.Verification, r.Header.Get("Accept-Language"), mail.VerificationData{
+		Name:           user.FirstName,
+		Link:           h.links.VerifyEmail(token),
+		ExpiresInHours: int(utils.EmailVerificationTTL.Hours()),
+	})
+	if err != nil {
+		middleware.Logger(r.Context()).Error("send verification email", "user_id", user.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// requireVerifiedEmail writes a 403 and returns false unless userID has
+// verified their email address.
+func (h *Handler) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, userID string) bool {
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondWithUserLookupError(w, err)
+		return false
+	}
+	if user.EmailVerifiedAt == nil {
+		respondWithError(w, apierror.EmailNotVerified, "Email address must be verified first")
+		return false
+	}
+	return true
+}
+
+// provisionQRCode creates the account's default upload link and stores it
+// encoded as a QR code. If any step fails, the QR code and link created so far
+// are removed again.
+func (h *Handler) provisionQRCode(ctx context.Context, userID string) error {
+	uploadToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	QRCode, err := utils.GenerateQRCode(ctx, h.store, userID, h.links.Upload(uploadToken))
+	if err != nil {
+		return err
+	}
+
+	// Clean up even if the request was cancelled
+	cleanupCtx := context.WithoutCancel(ctx)
+	link, err := h.createUploadLink(ctx, userID, uploadToken, CreateUploadLinkRequest{Name: "QR code"})
+	if err != nil {
+		_ = h.cleaner.DeleteAsset(cleanupCtx, QRCode.Key)
+		return err
+	}
+	if err := h.repos.Users.SetQRCode(ctx, userID, QRCode.URL, QRCode.Key); err != nil {
+		_ = h.cleaner.DeleteAsset(cleanupCtx, QRCode.Key)
+		if rerr := h.repos.UploadLinks.Revoke(cleanupCtx, link.ID, userID); rerr != nil {
+			middleware.Logger(ctx).Error("revoke unused QR code upload link", "upload_link_id", link.ID, "error", rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ReissueLegacyQRCodes gives every account whose QR code predates upload links
+// a default upload link and a QR code encoding it, and deletes the old code.
+// Old codes were uploaded to Cloudinary without recording a key, so theirs is
+// recovered from the URL.
+// Accounts the verification policy still holds back lose the old code and get
+// a new one once they verify. It returns how many accounts were updated.
+func (h *Handler) ReissueLegacyQRCodes(ctx context.Context) (int, error) {
+	var reissued int
+	after := ""
+	for {
+		users, err := h.repos.Users.ListLegacyQRCodes(ctx, after, 100)
+		if err != nil {
+			return reissued, err
+		}
+		if len(users) == 0 {
+			return reissued, nil
+		}
+		for _, user := range users {
+			after = user.ID
+			if h.verification.QRCode && user.EmailVerifiedAt == nil {
+				err = h.repos.Users.SetQRCode(ctx, user.ID, "", "")
+			} else {
+				err = h.provisionQRCode(ctx, user.ID)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return reissued, err
+				}
+				middleware.Logger(ctx).Error("reissue legacy QR code", "user_id", user.ID, "error", err)
+				continue
+			}
+			key := user.QRCodeKey
+			if key == "" {
+				key, _ = storage.CloudinaryPublicID(user.QRCodeLink)
+			}
+			if key != "" {
+				// Failures are queued for the background sweeper
+				_ = h.cleaner.DeleteAsset(ctx, key)
+			}
+			reissued++
+		}
+	}
+}
+
+// POST /verifyEmail?token=...
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithValidation(w, "Verification token is required in the query parameters", apierror.Required("token"))
+		return
+	}
+
+	userID, email, err := utils.ParseEmailVerificationToken(h.jwtSecret, token)
+	if err != nil {
+		respondWithError(w, apierror.InvalidToken, "Invalid or expired verification token")
+		return
+	}
+
+	// A token for an address the user has since changed no longer counts
+	newlyVerified, err := h.repos.Users.MarkEmailVerified(r.Context(), userID, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondWithError(w, apierror.InvalidToken, "Invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, apierror.Internal, "Failed to verify email")
+		return
+	}
+
+	// Hand out the QR code that was held back until now
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondWithUserLookupError(w, err)
+		return
+	}
+	if user.QRCodeKey == "" {
+		if err := h.provisionQRCode(r.Context(), userID); err != nil {
+			respondWithError(w, apierror.Internal, "Failed to generate QR code link")
+			return
+		}
+	}
+
+	// Only the first verification is worth a welcome
+	if newlyVerified {
+		err = h.mailer.Send(r.Context(), user.Email, mail.Welcome, r.Header.Get("Accept-Language"), mail.WelcomeData{
+			Name:   user.FirstName,
+			AppURL: h.links.App(),
+		})
+		if err != nil {
+			middleware.Logger(r.Context()).Error("send welcome email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// POST /resendVerificationEmail
+func (h *Handler) ResendVerificationEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, apierror.Unauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.repos.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		respondWithUserLookupError(w, err)
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		respondWithError(w, apierror.EmailVerified, "Email is already verified")
+		return
+	}
+
+	if err := h.sendVerificationEmail(r, user); err != nil {
+		respondWithError(w, apierror.Internal, "Failed to send verification email")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Verification email sent",
+	})
+}
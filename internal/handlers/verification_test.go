@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"Backend/internal/handlers"
+	"Backend/internal/repository"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReissueLegacyQRCodesDeletesOldAsset(t *testing.T) {
+	ts := newTestServer(t, "none", handlers.UploadLimits{})
+	ctx := context.Background()
+
+	// Accounts from before upload links only have the Cloudinary URL of their code
+	user := repository.User{ID: uuid.NewString(), Email: "legacy@example.com", PasswordHash: "x"}
+	if err := ts.repos.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	legacyURL := "https://res.cloudinary.com/demo/image/upload/v1700000000/" + user.ID + ".png"
+	if err := ts.repos.Users.SetQRCode(ctx, user.ID, legacyURL, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ts.h.ReissueLegacyQRCodes(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("ReissueLegacyQRCodes = %d, %v; want 1", n, err)
+	}
+	if !slices.Contains(ts.deletedKeys(), user.ID) {
+		t.Fatalf("deleted %v, want the legacy asset %q", ts.deletedKeys(), user.ID)
+	}
+
+	got, err := ts.repos.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.QRCodeLink == legacyURL || got.QRCodeKey == "" {
+		t.Fatalf("QR code not replaced: link %q, key %q", got.QRCodeLink, got.QRCodeKey)
+	}
+
+	// A second run finds nothing left to do
+	if n, err := ts.h.ReissueLegacyQRCodes(ctx); err != nil || n != 0 {
+		t.Fatalf("second ReissueLegacyQRCodes = %d, %v; want 0", n, err)
+	}
+}
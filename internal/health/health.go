@@ -0,0 +1,113 @@
+// Package health runs the dependency checks behind the readiness endpoint.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single check when Check.Timeout is zero.
+const DefaultTimeout = 3 * time.Second
+
+// Check probes one dependency.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Timeout bounds a single run; DefaultTimeout is used when zero.
+	Timeout time.Duration
+	// CacheFor reuses the last result for this long, for checks that are
+	// slow or rate limited (e.g. Cloudinary's admin API).
+	CacheFor time.Duration
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string    `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of every check. Status is "ok" only if all passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Checker runs a fixed set of checks concurrently.
+type Checker struct {
+	checks []Check
+
+	mu     sync.Mutex
+	cached map[string]Result
+}
+
+func New(checks ...Check) *Checker {
+	return &Checker{checks: checks, cached: map[string]Result{}}
+}
+
+// Run executes every check and reports their results.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	for i, check := range c.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusError
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	if check.CacheFor > 0 {
+		c.mu.Lock()
+		last, ok := c.cached[check.Name]
+		c.mu.Unlock()
+		if ok && time.Since(last.CheckedAt) < check.CacheFor {
+			return last
+		}
+	}
+
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	res := Result{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start.UTC(),
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Error = err.Error()
+	}
+
+	if check.CacheFor > 0 {
+		c.mu.Lock()
+		c.cached[check.Name] = res
+		c.mu.Unlock()
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// Metadata is the EXIF information we keep for an uploaded photo.
+type Metadata struct {
+	// CapturedAt is the camera's wall-clock time. EXIF rarely records a time
+	// zone, so it is returned in UTC with the wall-clock fields preserved.
+	CapturedAt  *time.Time
+	CameraMake  string
+	CameraModel string
+	// Orientation is the EXIF orientation tag (1-8); 0 when absent.
+	Orientation int
+	Latitude    *float64
+	Longitude   *float64
+}
+
+// ReadMetadata extracts EXIF metadata from an uploaded file. Files without
+// EXIF data yield an empty Metadata and a non-nil error.
+func ReadMetadata(data []byte) (Metadata, error) {
+	var m Metadata
+
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return m, err
+	}
+
+	if t, err := x.DateTime(); err == nil {
+		wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+		m.CapturedAt = &wall
+	}
+	m.CameraMake = stringTag(x, exif.Make)
+	m.CameraModel = stringTag(x, exif.Model)
+
+	if tag, err := x.Get(exif.Orientation); err == nil {
+		if v, err := tag.Int(0); err == nil && v >= 1 && v <= 8 {
+			m.Orientation = v
+		}
+	}
+
+	if lat, lng, err := x.LatLong(); err == nil {
+		m.Latitude, m.Longitude = &lat, &lng
+	}
+
+	return m, nil
+}
+
+func stringTag(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil {
+		return ""
+	}
+	s, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+// ApplyOrientation returns img transformed so it displays upright for the
+// given EXIF orientation. Orientations 0 and 1 return img unchanged.
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := image.NewRGBA(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			i := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			j := dst.PixOffset(dx, dy)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+		}
+	}
+	return dst
+}
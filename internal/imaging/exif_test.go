@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+// exifJPEG encodes img as a JPEG carrying an APP1 segment with the given
+// camera make, model, DateTime and Orientation tags.
+func exifJPEG(t *testing.T, img image.Image, cameraMake, model, dateTime string, orientation uint16) []byte {
+	t.Helper()
+
+	type entry struct {
+		tag   uint16
+		ascii string
+		short uint16
+	}
+	entries := []entry{
+		{tag: 0x010f, ascii: cameraMake},
+		{tag: 0x0110, ascii: model},
+		{tag: 0x0112, short: orientation},
+		{tag: 0x0132, ascii: dateTime},
+	}
+
+	le := binary.LittleEndian
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	ifd := le.AppendUint16(nil, uint16(len(entries)))
+	// Strings that don't fit in an entry follow the IFD and its next-IFD offset
+	dataOffset := 8 + 2 + 12*len(entries) + 4
+	var data []byte
+	for _, e := range entries {
+		ifd = le.AppendUint16(ifd, e.tag)
+		if e.ascii == "" {
+			ifd = le.AppendUint16(ifd, 3)
+			ifd = le.AppendUint32(ifd, 1)
+			ifd = le.AppendUint16(ifd, e.short)
+			ifd = le.AppendUint16(ifd, 0)
+			continue
+		}
+		s := append([]byte(e.ascii), 0)
+		ifd = le.AppendUint16(ifd, 2)
+		ifd = le.AppendUint32(ifd, uint32(len(s)))
+		ifd = le.AppendUint32(ifd, uint32(dataOffset+len(data)))
+		data = append(data, s...)
+	}
+	ifd = le.AppendUint32(ifd, 0)
+	tiff = append(append(tiff, ifd...), data...)
+
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xff, 0xd8, 0xff, 0xe1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(app1)+2))
+	out = append(out, app1...)
+	// Skip the encoder's own SOI marker
+	return append(out, enc.Bytes()[2:]...)
+}
+
+func TestReadMetadata(t *testing.T) {
+	data := exifJPEG(t, solid(4, 2), "Canon", "EOS R6", "2024:06:01 18:30:05", 6)
+
+	m, err := ReadMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CameraMake != "Canon" || m.CameraModel != "EOS R6" {
+		t.Errorf("camera = %q %q, want Canon EOS R6", m.CameraMake, m.CameraModel)
+	}
+	if m.Orientation != 6 {
+		t.Errorf("Orientation = %d, want 6", m.Orientation)
+	}
+	want := time.Date(2024, 6, 1, 18, 30, 5, 0, time.UTC)
+	if m.CapturedAt == nil || !m.CapturedAt.Equal(want) {
+		t.Errorf("CapturedAt = %v, want %v", m.CapturedAt, want)
+	}
+	if m.Latitude != nil || m.Longitude != nil {
+		t.Errorf("got a location without GPS tags: %v, %v", m.Latitude, m.Longitude)
+	}
+}
+
+func TestReadMetadataIgnoresInvalidOrientation(t *testing.T) {
+	m, err := ReadMetadata(exifJPEG(t, solid(1, 1), "Canon", "EOS R6", "2024:06:01 18:30:05", 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Orientation != 0 {
+		t.Errorf("Orientation = %d, want 0", m.Orientation)
+	}
+}
+
+func TestReadMetadataWithoutExif(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, solid(1, 1), nil); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadMetadata(buf.Bytes())
+	if err == nil {
+		t.Error("ReadMetadata succeeded on a JPEG without EXIF")
+	}
+	if m != (Metadata{}) {
+		t.Errorf("Metadata = %+v, want zero", m)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image with a red top-left pixel
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		// where the top-left pixel ends up
+		x, y int
+	}{
+		{0, 3, 2, 0, 0},
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tt := range tests {
+		out := ApplyOrientation(src, tt.orientation)
+		b := out.Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if r, _, _, _ := out.At(tt.x, tt.y).RGBA(); r != 0xffff {
+			t.Errorf("orientation %d: top-left pixel not at (%d, %d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
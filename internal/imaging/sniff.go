@@ -0,0 +1,83 @@
+package imaging
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// ftypBrands maps ISO base media file brands that http.DetectContentType
+// does not recognise to their content type. Phones save photos as HEIC and
+// videos as QuickTime, so both must be accepted.
+var ftypBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic-sequence",
+	"hevx": "image/heic-sequence",
+	"mif1": "image/heif",
+	"msf1": "image/heif-sequence",
+	"avif": "image/avif",
+	"avis": "image/avif",
+	"qt  ": "video/quicktime",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+	"3gp6": "video/3gpp",
+	"3g2a": "video/3gpp2",
+}
+
+// DetectContentType identifies a file from its first bytes, ignoring the
+// name and content type the client claimed.
+func DetectContentType(head []byte) string {
+	// An ISO BMFF file opens with a box of type "ftyp" followed by the major brand
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		if ct, ok := ftypBrands[string(head[8:12])]; ok {
+			return ct
+		}
+	}
+	ct := http.DetectContentType(head)
+	ct, _, _ = strings.Cut(ct, ";")
+	return ct
+}
+
+// IsMedia reports whether contentType is an image or video guests may upload.
+func IsMedia(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/")
+}
+
+// extensions maps detected content types to the file extension stored
+// objects get, so keys never depend on the name the client sent.
+var extensions = map[string]string{
+	"image/jpeg":          ".jpg",
+	"image/png":           ".png",
+	"image/gif":           ".gif",
+	"image/webp":          ".webp",
+	"image/bmp":           ".bmp",
+	"image/heic":          ".heic",
+	"image/heic-sequence": ".heics",
+	"image/heif":          ".heif",
+	"image/heif-sequence": ".heifs",
+	"image/avif":          ".avif",
+	"video/mp4":           ".mp4",
+	"video/webm":          ".webm",
+	"video/avi":           ".avi",
+	"video/quicktime":     ".mov",
+	"video/3gpp":          ".3gp",
+	"video/3gpp2":         ".3g2",
+}
+
+// Extension returns the file extension for a type returned by
+// DetectContentType, or "" when it has none.
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// Decodable reports whether Decode can read images of contentType.
+func Decodable(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
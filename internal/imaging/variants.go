@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	// Register decoders for the formats guests upload from phones and cameras
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Variant names, ordered from smallest to largest.
+const (
+	Thumbnail = "thumbnail"
+	Medium    = "medium"
+	Original  = "original"
+)
+
+// Variant is a resized rendition of an uploaded image, encoded as JPEG.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// sizes is the longest edge, in pixels, of each generated variant.
+var sizes = []struct {
+	name    string
+	maxEdge int
+}{
+	{Thumbnail, 320},
+	{Medium, 1280},
+}
+
+const jpegQuality = 82
+
+// MaxPixels is the largest image Decode accepts. A few kilobytes of PNG or
+// JPEG can declare dimensions that decode to gigabytes, so the header is
+// checked before any pixels are allocated.
+const MaxPixels = 50_000_000
+
+// ErrTooManyPixels is returned by Decode for images larger than MaxPixels.
+var ErrTooManyPixels = errors.New("image dimensions exceed the pixel limit")
+
+// Decode decodes an uploaded image. It returns image.ErrFormat for files that
+// are not a supported image format (e.g. videos) and ErrTooManyPixels for
+// oversized ones; callers should store both without variants.
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, format, ErrTooManyPixels
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// Generate produces the thumbnail and medium variants of img. Variants are
+// never upscaled: when the source is already smaller than a variant's bound,
+// it is re-encoded at its own size.
+func Generate(img image.Image) ([]Variant, error) {
+	var variants []Variant
+	for _, size := range sizes {
+		scaled := Resize(img, size.maxEdge)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %v", size.name, err)
+		}
+
+		b := scaled.Bounds()
+		variants = append(variants, Variant{
+			Name:   size.name,
+			Width:  b.Dx(),
+			Height: b.Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+	return variants, nil
+}
+
+// Resize scales img so its longest edge is at most maxEdge, keeping the aspect ratio.
+func Resize(img image.Image, maxEdge int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return img
+	}
+
+	if w >= h {
+		h = max(h*maxEdge/w, 1)
+		w = maxEdge
+	} else {
+		w = max(w*maxEdge/h, 1)
+		h = maxEdge
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
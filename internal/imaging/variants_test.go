@@ -0,0 +1,112 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solid(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		w, h, maxEdge int
+		wantW, wantH  int
+	}{
+		{4000, 3000, 1280, 1280, 960},
+		{3000, 4000, 320, 240, 320},
+		{500, 500, 320, 320, 320},
+		{200, 100, 320, 200, 100},
+		{5000, 2, 320, 320, 1},
+	}
+	for _, tt := range tests {
+		b := Resize(solid(tt.w, tt.h), tt.maxEdge).Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("Resize(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.maxEdge, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	variants, err := Generate(solid(2000, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name string
+		w, h int
+	}{
+		{Thumbnail, 320, 160},
+		{Medium, 1280, 640},
+	}
+	if len(variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(variants), len(want))
+	}
+	for i, v := range variants {
+		if v.Name != want[i].name || v.Width != want[i].w || v.Height != want[i].h {
+			t.Errorf("variant %d = %s %dx%d, want %s %dx%d", i, v.Name, v.Width, v.Height, want[i].name, want[i].w, want[i].h)
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Errorf("%s is not a JPEG: %v", v.Name, err)
+			continue
+		}
+		if cfg.Width != v.Width || cfg.Height != v.Height {
+			t.Errorf("%s encodes %dx%d, reports %dx%d", v.Name, cfg.Width, cfg.Height, v.Width, v.Height)
+		}
+	}
+}
+
+func TestGenerateDoesNotUpscale(t *testing.T) {
+	variants, err := Generate(solid(100, 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range variants {
+		if v.Width != 100 || v.Height != 50 {
+			t.Errorf("%s = %dx%d, want 100x50", v.Name, v.Width, v.Height)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(3, 2)); err != nil {
+		t.Fatal(err)
+	}
+	img, format, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+		t.Errorf("Decode = %s %v, want png 3x2", format, img.Bounds())
+	}
+
+	if _, _, err := Decode([]byte("not an image")); !errors.Is(err, image.ErrFormat) {
+		t.Errorf("Decode(text) error = %v, want image.ErrFormat", err)
+	}
+}
+
+func TestDecodeRejectsTooManyPixels(t *testing.T) {
+	// A paletted PNG compresses to a few kilobytes whatever its dimensions,
+	// so this is the kind of file the header check exists for.
+	pal := image.NewPaletted(image.Rect(0, 0, 10_000, 5_001), color.Palette{color.Black})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pal); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Decode(buf.Bytes()); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("Decode error = %v, want ErrTooManyPixels", err)
+	}
+}
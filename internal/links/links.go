@@ -0,0 +1,75 @@
+// Package links builds the public frontend URLs that the API hands out in
+// emails, QR codes and responses.
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Frontend pages that consume the tokens the API issues.
+const (
+	resetPasswordPath = "/reset-password"
+	verifyEmailPath   = "/verify-email"
+	uploadPath        = "/upload"
+)
+
+// Builder turns tokens into deep links below a validated frontend base URL.
+type Builder struct {
+	base *url.URL
+}
+
+// New validates base, which must be an absolute http(s) URL without query,
+// fragment or credentials. A path prefix such as "/app" is kept.
+func New(base string) (*Builder, error) {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		return nil, fmt.Errorf("frontend URL is not set")
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("frontend URL %q: %w", base, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("frontend URL %q must use http or https", base)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("frontend URL %q has no host", base)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("frontend URL %q must not contain credentials, a query or a fragment", base)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return &Builder{base: u}, nil
+}
+
+// App is the frontend's home page.
+func (b *Builder) App() string {
+	return b.page("/", "")
+}
+
+// ResetPassword is the page that submits a password reset token.
+func (b *Builder) ResetPassword(token string) string {
+	return b.page(resetPasswordPath, token)
+}
+
+// VerifyEmail is the page that submits an email verification token.
+func (b *Builder) VerifyEmail(token string) string {
+	return b.page(verifyEmailPath, token)
+}
+
+// Upload is the guest-facing page an upload link token opens.
+func (b *Builder) Upload(token string) string {
+	return b.page(uploadPath, token)
+}
+
+func (b *Builder) page(path, token string) string {
+	u := *b.base
+	u.Path += path
+	if token != "" {
+		u.RawQuery = url.Values{"token": {token}}.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,184 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// DefaultLocale is used when a message is requested in a locale that has no
+// templates of its own.
+const DefaultLocale = "en"
+
+// Template names one kind of email. Every locale directory under templates/
+// provides <name>.txt.tmpl, defining "subject" and "text" blocks prefixed with
+// the name, and <name>.html.tmpl, defining a "<name>.html" block.
+type Template string
+
+const (
+	Reset        Template = "reset"
+	Verification Template = "verification"
+	Welcome      Template = "welcome"
+	UploadDigest Template = "upload_digest"
+)
+
+var allTemplates = []Template{Reset, Verification, Welcome, UploadDigest}
+
+type ResetData struct {
+	Name             string
+	Link             string
+	ExpiresInMinutes int
+}
+
+type VerificationData struct {
+	Name           string
+	Link           string
+	ExpiresInHours int
+}
+
+type WelcomeData struct {
+	Name   string
+	AppURL string
+}
+
+// UploadDigestData summarises the guest uploads a user received since a time.
+type UploadDigestData struct {
+	Name   string
+	Since  time.Time
+	Links  []DigestLink
+	Total  int
+	AppURL string
+}
+
+type DigestLink struct {
+	Name  string
+	Count int
+}
+
+type localeTemplates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Mailer renders templated emails and hands them to a Transport.
+type Mailer struct {
+	transport Transport
+	from      string
+	locales   map[string]localeTemplates
+}
+
+// New parses the embedded templates and returns a Mailer sending from from.
+func New(transport Transport, from string) (*Mailer, error) {
+	entries, err := fs.ReadDir(templateFiles, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mailer{transport: transport, from: from, locales: map[string]localeTemplates{}}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		locale := entry.Name()
+		dir := path.Join("templates", locale)
+		text, err := texttemplate.ParseFS(templateFiles, dir+"/*.txt.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("mail templates %s: %v", locale, err)
+		}
+		html, err := htmltemplate.ParseFS(templateFiles, dir+"/*.html.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("mail templates %s: %v", locale, err)
+		}
+		m.locales[locale] = localeTemplates{text: text, html: html}
+	}
+
+	def, ok := m.locales[DefaultLocale]
+	if !ok {
+		return nil, fmt.Errorf("mail templates: missing default locale %q", DefaultLocale)
+	}
+	for _, t := range allTemplates {
+		if !def.has(t) {
+			return nil, fmt.Errorf("mail templates: %s is incomplete for locale %q", t, DefaultLocale)
+		}
+	}
+	return m, nil
+}
+
+func (l localeTemplates) has(t Template) bool {
+	name := string(t)
+	return l.text.Lookup(name+".subject") != nil && l.text.Lookup(name+".text") != nil && l.html.Lookup(name+".html") != nil
+}
+
+// Send renders t in locale (falling back to DefaultLocale) and sends it to to.
+func (m *Mailer) Send(ctx context.Context, to string, t Template, locale string, data any) error {
+	msg, err := m.Render(to, t, locale, data)
+	if err != nil {
+		return err
+	}
+	return m.transport.Send(ctx, msg)
+}
+
+// Check reports whether the transport could deliver mail right now.
+func (m *Mailer) Check(ctx context.Context) error {
+	return m.transport.Check(ctx)
+}
+
+// Render builds the message Send would deliver.
+func (m *Mailer) Render(to string, t Template, locale string, data any) (*Message, error) {
+	tmpl, ok := m.locales[m.MatchLocale(locale)]
+	if !ok || !tmpl.has(t) {
+		tmpl = m.locales[DefaultLocale]
+	}
+	if !tmpl.has(t) {
+		return nil, fmt.Errorf("mail: unknown template %q", t)
+	}
+
+	var subject, text, html bytes.Buffer
+	name := string(t)
+	if err := tmpl.text.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, name+".text", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		From:    m.from,
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+		Date:    time.Now(),
+	}, nil
+}
+
+// MatchLocale picks the best supported locale for an Accept-Language header
+// value (or a plain tag such as "de-AT"), defaulting to DefaultLocale.
+func (m *Mailer) MatchLocale(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if _, ok := m.locales[tag]; ok {
+			return tag
+		}
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			if _, ok := m.locales[base]; ok {
+				return base
+			}
+		}
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,128 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMailer(t *testing.T) (*Mailer, *LogTransport) {
+	t.Helper()
+	transport := &LogTransport{Logger: discardLogger()}
+	m, err := New(transport, "Photos <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, transport
+}
+
+func TestEveryLocaleHasEveryTemplate(t *testing.T) {
+	m, _ := newTestMailer(t)
+	if len(m.locales) < 2 {
+		t.Fatalf("found %d locales, want en and de", len(m.locales))
+	}
+	for locale, tmpl := range m.locales {
+		for _, name := range allTemplates {
+			if !tmpl.has(name) {
+				t.Errorf("locale %s is missing %s", locale, name)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	m, _ := newTestMailer(t)
+	data := map[Template]any{
+		Reset:        ResetData{Name: "Ada", Link: "https://app.example.com/reset?token=abc", ExpiresInMinutes: 30},
+		Verification: VerificationData{Name: "Ada", Link: "https://app.example.com/verify?token=abc", ExpiresInHours: 24},
+		Welcome:      WelcomeData{Name: "Ada", AppURL: "https://app.example.com"},
+		UploadDigest: UploadDigestData{
+			Name:   "Ada",
+			Since:  time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+			Links:  []DigestLink{{Name: "Wedding", Count: 3}},
+			Total:  3,
+			AppURL: "https://app.example.com",
+		},
+	}
+
+	for _, locale := range []string{"en", "de"} {
+		for name, d := range data {
+			msg, err := m.Render("ada@example.com", name, locale, d)
+			if err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+				continue
+			}
+			if msg.Subject == "" || msg.Text == "" || msg.HTML == "" {
+				t.Errorf("%s/%s: empty subject, text or HTML", locale, name)
+			}
+			if !strings.Contains(msg.Text, "Ada") || !strings.Contains(msg.HTML, "Ada") {
+				t.Errorf("%s/%s: name missing from the body", locale, name)
+			}
+			if strings.Contains(msg.Text, "<no value>") || strings.Contains(msg.HTML, "<no value>") {
+				t.Errorf("%s/%s: template uses a field the data lacks", locale, name)
+			}
+		}
+	}
+}
+
+func TestRenderPicksLocale(t *testing.T) {
+	m, _ := newTestMailer(t)
+	data := ResetData{Link: "https://app.example.com/reset", ExpiresInMinutes: 30}
+
+	tests := []struct {
+		locale  string
+		subject string
+	}{
+		{"", "Reset your password"},
+		{"en", "Reset your password"},
+		{"de", "Passwort zurücksetzen"},
+		{"de-AT,en;q=0.8", "Passwort zurücksetzen"},
+		{"fr-FR,fr;q=0.9", "Reset your password"},
+	}
+	for _, tt := range tests {
+		msg, err := m.Render("ada@example.com", Reset, tt.locale, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != tt.subject {
+			t.Errorf("locale %q: subject %q, want %q", tt.locale, msg.Subject, tt.subject)
+		}
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	m, _ := newTestMailer(t)
+	msg, err := m.Render("ada@example.com", Welcome, "en", WelcomeData{Name: "<b>Ada</b>", AppURL: "https://app.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTML, "<b>Ada</b>") || !strings.Contains(msg.HTML, "&lt;b&gt;Ada&lt;/b&gt;") {
+		t.Error("name is not escaped in the HTML part")
+	}
+	if !strings.Contains(msg.Text, "<b>Ada</b>") {
+		t.Error("name is escaped in the text part")
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	m, _ := newTestMailer(t)
+	if _, err := m.Render("ada@example.com", Template("nope"), "en", nil); err == nil {
+		t.Error("rendered an unknown template")
+	}
+}
+
+func TestSend(t *testing.T) {
+	m, transport := newTestMailer(t)
+	if err := m.Send(context.Background(), "ada@example.com", Welcome, "de", WelcomeData{Name: "Ada", AppURL: "https://app.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := transport.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if sent[0].From != "Photos <noreply@example.com>" || len(sent[0].To) != 1 || sent[0].To[0] != "ada@example.com" {
+		t.Errorf("message from %q to %v", sent[0].From, sent[0].To)
+	}
+}
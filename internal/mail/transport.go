@@ -0,0 +1,234 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport delivers rendered messages.
+type Transport interface {
+	Send(ctx context.Context, m *Message) error
+	// Check reports whether messages could be delivered right now.
+	Check(ctx context.Context) error
+}
+
+// SMTPTransport sends mail through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS. Authentication is skipped if Username is empty.
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, m *Message) error {
+	from, err := m.envelopeFrom()
+	if err != nil {
+		return err
+	}
+	body, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, done, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	// The same steps as smtp.SendMail, on a connection that honours ctx
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: t.Host}); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Check connects to the server and says hello without sending anything.
+func (t *SMTPTransport) Check(ctx context.Context) error {
+	c, done, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return c.Quit()
+}
+
+// smtpTimeout bounds an SMTP exchange whose context has no earlier deadline,
+// so a server that stops responding cannot hold a request forever.
+const smtpTimeout = 30 * time.Second
+
+// dial connects to the server and says hello. Every read and write on the
+// connection fails once ctx is done or smtpTimeout has passed; done closes
+// the client and must be called.
+func (t *SMTPTransport) dial(ctx context.Context) (c *smtp.Client, done func(), err error) {
+	if t.Host == "" || t.Port == "" {
+		return nil, nil, fmt.Errorf("SMTP host and port are not configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(t.Host, t.Port))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	// Unblock pending I/O as soon as the caller gives up
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+
+	c, err = smtp.NewClient(conn, t.Host)
+	if err == nil {
+		err = c.Hello("localhost")
+	}
+	if err != nil {
+		stop()
+		conn.Close()
+		cancel()
+		return nil, nil, err
+	}
+	return c, func() {
+		stop()
+		c.Close()
+		cancel()
+	}, nil
+}
+
+// FileTransport writes each message as an .eml file into Dir instead of
+// sending it, so local development needs no mail server.
+type FileTransport struct {
+	Dir string
+}
+
+// Check makes sure the outbox directory exists.
+func (t *FileTransport) Check(ctx context.Context) error {
+	return os.MkdirAll(t.Dir, 0o755)
+}
+
+func (t *FileTransport) Send(ctx context.Context, m *Message) error {
+	body, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(strings.Join(m.To, ","))
+	name := fmt.Sprintf("%s_%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), recipient)
+	path := filepath.Join(t.Dir, name)
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return err
+	}
+	log.Printf("mail: wrote %q for %s to %s", m.Subject, strings.Join(m.To, ", "), path)
+	return nil
+}
+
+// LogTransport logs each message and keeps it in memory. It is meant for
+// tests, which can inspect what would have been sent with Messages.
+type LogTransport struct {
+	Logger *log.Logger
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (t *LogTransport) Check(ctx context.Context) error { return nil }
+
+func (t *LogTransport) Send(ctx context.Context, m *Message) error {
+	logf := log.Printf
+	if t.Logger != nil {
+		logf = t.Logger.Printf
+	}
+	logf("mail: %q to %s\n%s", m.Subject, strings.Join(m.To, ", "), m.Text)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = append(t.sent, *m)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far.
+func (t *LogTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.sent...)
+}
+
+// Config selects and configures the mail transport.
+type Config struct {
+	// Transport is "smtp" (the default), "file" or "log".
+	Transport string `yaml:"transport"`
+	// Sender is the From address of every message.
+	Sender string     `yaml:"sender"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+	// OutboxDir is where the file transport writes messages.
+	OutboxDir string `yaml:"outbox_dir"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// NewTransport builds the transport named by cfg.Transport.
+func NewTransport(cfg Config) (Transport, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(cfg.Transport)); backend {
+	case "", "smtp":
+		return &SMTPTransport{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}, nil
+	case "file":
+		dir := cfg.OutboxDir
+		if dir == "" {
+			dir = "outbox"
+		}
+		return &FileTransport{Dir: dir}, nil
+	case "log":
+		return &LogTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", backend)
+	}
+}
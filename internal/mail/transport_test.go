@@ -0,0 +1,325 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func testMessage() *Message {
+	return &Message{
+		From:    "Photos <noreply@example.com>",
+		To:      []string{"ada@example.com"},
+		Subject: "Passwort zurücksetzen",
+		Text:    "Hallo Ada,\nein sehr langer Satz, der über die zulässige Zeilenlänge von quoted-printable hinausgeht, damit er umbrochen werden muss.",
+		HTML:    `<p style="color: #333">Hallo Ada</p>`,
+		Date:    time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// parseMessage parses an encoded message and returns its headers and the
+// decoded body of each part by content type.
+func parseMessage(t *testing.T, raw []byte) (netmail.Header, map[string]string) {
+	t.Helper()
+
+	msg, err := netmail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type %s, want multipart/alternative", mediaType)
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The reader undoes the quoted-printable transfer encoding
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[p.Header.Get("Content-Type")] = string(body)
+	}
+	return msg.Header, parts
+}
+
+func TestMessageBytes(t *testing.T) {
+	m := testMessage()
+	raw, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatal("line longer than RFC 5322 allows")
+		}
+	}
+
+	header, parts := parseMessage(t, raw)
+	if header.Get("From") != m.From || header.Get("To") != "ada@example.com" {
+		t.Errorf("From %q, To %q", header.Get("From"), header.Get("To"))
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil || subject != m.Subject {
+		t.Errorf("Subject decodes to %q (%v), want %q", subject, err, m.Subject)
+	}
+	if date, err := header.Date(); err != nil || !date.Equal(m.Date) {
+		t.Errorf("Date %v (%v), want %v", date, err, m.Date)
+	}
+	if id := header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID %q is not on the sender's domain", id)
+	}
+	if header.Get("MIME-Version") != "1.0" {
+		t.Error("missing MIME-Version")
+	}
+
+	// Quoted-printable text mode turns line breaks into CRLF
+	if text := strings.ReplaceAll(parts["text/plain; charset=utf-8"], "\r\n", "\n"); text != m.Text {
+		t.Errorf("text part %q, want %q", text, m.Text)
+	}
+	if parts["text/html; charset=utf-8"] != m.HTML {
+		t.Errorf("HTML part %q, want %q", parts["text/html; charset=utf-8"], m.HTML)
+	}
+}
+
+func TestMessageBytesSkipsEmptyParts(t *testing.T) {
+	m := testMessage()
+	m.HTML = ""
+	raw, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, parts := parseMessage(t, raw); len(parts) != 1 {
+		t.Errorf("got %d parts, want only the text part", len(parts))
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	transport := &FileTransport{Dir: dir}
+	if err := transport.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := transport.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*_ada_at_example.com.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("found %v (%v), want one .eml file", files, err)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, parts := parseMessage(t, raw); len(parts) != 2 {
+		t.Errorf("written message has %d parts, want 2", len(parts))
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		transport string
+		want      Transport
+	}{
+		{"", &SMTPTransport{}},
+		{"SMTP", &SMTPTransport{}},
+		{"file", &FileTransport{}},
+		{"log", &LogTransport{}},
+	}
+	for _, tt := range tests {
+		got, err := NewTransport(Config{Transport: tt.transport})
+		if err != nil {
+			t.Errorf("%q: %v", tt.transport, err)
+			continue
+		}
+		if gotType, wantType := fmt.Sprintf("%T", got), fmt.Sprintf("%T", tt.want); gotType != wantType {
+			t.Errorf("%q: got %s, want %s", tt.transport, gotType, wantType)
+		}
+	}
+	if ft, _ := NewTransport(Config{Transport: "file"}); ft.(*FileTransport).Dir != "outbox" {
+		t.Error("file transport does not default to ./outbox")
+	}
+	if _, err := NewTransport(Config{Transport: "carrier-pigeon"}); err == nil {
+		t.Error("accepted an unknown transport")
+	}
+}
+
+// fakeSMTP accepts one session on a local port, answering every command
+// with success, and returns what the client sent.
+type fakeSMTP struct {
+	addr     string
+	commands chan []string
+	data     chan string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSMTP{addr: ln.Addr().String(), commands: make(chan []string, 1), data: make(chan string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 fake ESMTP")
+
+		var commands []string
+		var data strings.Builder
+		defer func() {
+			s.commands <- commands
+			s.data <- data.String()
+		}()
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			commands = append(commands, cmd)
+			switch verb, _, _ := strings.Cut(strings.ToUpper(cmd), " "); verb {
+			case "EHLO":
+				reply("250 fake")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPTransportSend(t *testing.T) {
+	server := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	transport := &SMTPTransport{Host: host, Port: port}
+
+	m := testMessage()
+	m.To = []string{"ada@example.com", "bob@example.com"}
+	if err := transport.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := <-server.commands
+	want := []string{
+		"EHLO localhost",
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<ada@example.com>",
+		"RCPT TO:<bob@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	for i, cmd := range commands {
+		// Newer clients append ESMTP parameters such as BODY=8BITMIME
+		if i >= len(want) || !strings.HasPrefix(cmd, want[i]) {
+			t.Fatalf("commands %q, want %q", commands, want)
+		}
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("commands %q, want %q", commands, want)
+	}
+	if _, parts := parseMessage(t, []byte(<-server.data)); parts["text/html; charset=utf-8"] != m.HTML {
+		t.Error("server did not receive the HTML part")
+	}
+}
+
+func TestSMTPTransportHonoursContext(t *testing.T) {
+	// A server that accepts but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := (&SMTPTransport{Host: host, Port: port}).Check(ctx); err == nil {
+		t.Fatal("Check succeeded against a silent server")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Check did not give up when the context expired")
+	}
+}
+
+func TestSMTPTransportRequiresHost(t *testing.T) {
+	if err := (&SMTPTransport{}).Check(context.Background()); err == nil {
+		t.Error("Check succeeded without a host")
+	}
+}
+
+func TestLogTransport(t *testing.T) {
+	transport := &LogTransport{Logger: discardLogger()}
+	for range 2 {
+		if err := transport.Send(context.Background(), testMessage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := transport.Messages()
+	if len(sent) != 2 {
+		t.Fatalf("kept %d messages, want 2", len(sent))
+	}
+	sent[0].Subject = "changed"
+	if transport.Messages()[0].Subject == "changed" {
+		t.Error("Messages does not return a copy")
+	}
+}
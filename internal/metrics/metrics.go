@@ -0,0 +1,60 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, uploads,
+// storage calls and the database pool.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric served by Handler. It is separate from the
+// Prometheus default registry so libraries can't add to it unnoticed.
+var Registry = prometheus.NewRegistry()
+
+// Upload outcomes.
+const (
+	UploadStored   = "stored"   // saved with its variants
+	UploadRejected = "rejected" // refused: too large, unsupported type or over the link's limits
+	UploadFailed   = "failed"   // could not be read, stored or recorded
+)
+
+var (
+	uploadFiles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upload_files_total",
+		Help: "Files received by upload requests, by outcome.",
+	}, []string{"outcome"})
+	uploadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upload_bytes_total",
+		Help: "Bytes received by upload requests, by outcome.",
+	}, []string{"outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		uploadFiles, uploadBytes,
+		storageDuration, storageErrors,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports db's connection pool statistics under the given name.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveUpload records one uploaded file of size bytes.
+func ObserveUpload(outcome string, size int64) {
+	uploadFiles.WithLabelValues(outcome).Inc()
+	uploadBytes.WithLabelValues(outcome).Add(float64(size))
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"Backend/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "storage_operation_duration_seconds",
+		Help:    "Time spent in storage backend calls, by backend and operation.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"backend", "operation"})
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_operation_errors_total",
+		Help: "Failed storage backend calls, by backend and operation.",
+	}, []string{"backend", "operation"})
+)
+
+// InstrumentStorage wraps s so that its calls are timed and counted under
+// the given backend name.
+func InstrumentStorage(s storage.Storage, backend string) storage.Storage {
+	return &instrumentedStorage{next: s, backend: backend}
+}
+
+type instrumentedStorage struct {
+	next    storage.Storage
+	backend string
+}
+
+func (s *instrumentedStorage) observe(op string, start time.Time, err error) {
+	storageDuration.WithLabelValues(s.backend, op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(s.backend, op).Inc()
+	}
+}
+
+func (s *instrumentedStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.Object, error) {
+	start := time.Now()
+	obj, err := s.next.Put(ctx, key, r, contentType)
+	s.observe("put", start, err)
+	return obj, err
+}
+
+func (s *instrumentedStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := s.next.Get(ctx, key)
+	s.observe("get", start, err)
+	return rc, err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, key)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	start := time.Now()
+	url, err := s.next.SignedURL(ctx, key, ttl)
+	s.observe("signed_url", start, err)
+	return url, err
+}
+
+func (s *instrumentedStorage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	s.observe("ping", start, err)
+	return err
+}
@@ -1,10 +1,10 @@
 package middleware
 
 import (
+	"Backend/internal/apierror"
+	"Backend/internal/repository"
 	"context"
-	"encoding/json"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,44 +12,65 @@ import (
 
 type contextKey string
 
-const UserIDKey = contextKey("user_id")
+const (
+	UserIDKey    = contextKey("user_id")
+	SessionIDKey = contextKey("session_id")
+)
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+// NewAuthMiddleware returns middleware that accepts access tokens signed with
+// secret whose session is still active in sessions.
+func NewAuthMiddleware(secret []byte, sessions repository.SessionRepo) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authMiddleware(secret, sessions, next)
+	}
+}
 
-func AuthMiddleware(next http.Handler) http.Handler {
+func authMiddleware(secret []byte, sessions repository.SessionRepo, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized - Missing Bearer token")
+			apierror.Respond(w, apierror.Unauthorized, "Unauthorized - Missing Bearer token")
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
+			return secret, nil
 		})
 
 		if err != nil || !token.Valid {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized - Invalid token")
+			apierror.Respond(w, apierror.InvalidToken, "Unauthorized - Invalid token")
 			return
 		}
 
+		// Single-purpose tokens (e.g. email verification) never authenticate API calls
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["user_id"] == nil {
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized - Invalid token claims")
+		if !ok || claims["user_id"] == nil || claims["purpose"] != nil {
+			apierror.Respond(w, apierror.InvalidToken, "Unauthorized - Invalid token claims")
+			return
+		}
+
+		userID, _ := claims["user_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		if userID == "" || sessionID == "" {
+			apierror.Respond(w, apierror.InvalidToken, "Unauthorized - Invalid token claims")
+			return
+		}
+
+		// Reject tokens whose session was revoked by logout, password change or account deletion
+		active, err := sessions.Active(r.Context(), sessionID, userID)
+		if err != nil {
+			apierror.Respond(w, apierror.Internal, "Failed to verify session")
+			return
+		}
+		if !active {
+			apierror.Respond(w, apierror.SessionRevoked, "Unauthorized - Session revoked or expired")
 			return
 		}
 
-		userID := claims["user_id"].(string)
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		ctx = withUser(ctx, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
-
-func respondWithError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
-}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"Backend/internal/apierror"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in both directions. A well-formed ID
+// sent by a client or proxy is kept so traces line up across services.
+const RequestIDHeader = apierror.RequestIDHeader
+
+const (
+	RequestIDKey  = contextKey("request_id")
+	loggerKey     = contextKey("logger")
+	requestLogKey = contextKey("request_log")
+)
+
+// requestLog collects fields that inner middleware learns while the request
+// is being served, such as the authenticated user.
+type requestLog struct {
+	userID string
+}
+
+// quietPaths are polled by infrastructure and only logged at debug level.
+var quietPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// NewRequestLogger returns middleware that assigns every request an ID,
+// echoes it in the X-Request-ID response header and logs one line per
+// request once it completes. It must be installed on the root router.
+func NewRequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			rl := &requestLog{}
+			ctx := context.WithValue(r.Context(), RequestIDKey, id)
+			ctx = context.WithValue(ctx, requestLogKey, rl)
+			ctx = context.WithValue(ctx, loggerKey, logger.With("request_id", id))
+
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := ""
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			case quietPaths[r.URL.Path]:
+				level = slog.LevelDebug
+			}
+
+			attrs := []slog.Attr{
+				slog.String("request_id", id),
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if rl.userID != "" {
+				attrs = append(attrs, slog.String("user_id", rl.userID))
+			}
+			logger.LogAttrs(ctx, level, "request", attrs...)
+		})
+	}
+}
+
+// validRequestID accepts short IDs made of URL-safe characters, so a client
+// can't inject arbitrary text into logs and responses.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the ID assigned by the request logger, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// Logger returns a logger carrying the request's ID and, once authenticated,
+// its user ID. Outside a request it returns slog.Default().
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// withUser records the authenticated user on the request's log line and
+// logger.
+func withUser(ctx context.Context, userID string) context.Context {
+	if rl, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		rl.userID = userID
+	}
+	return context.WithValue(ctx, loggerKey, Logger(ctx).With("user_id", userID))
+}
@@ -0,0 +1,812 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memStore holds every table in memory behind a single lock, which keeps
+// cross-table operations such as Purge atomic just like a transaction would.
+type memStore struct {
+	mu          sync.Mutex
+	users       map[string]User
+	folders     map[string]Folder
+	images      map[string]Image
+	variants    map[string][]ImageVariant
+	sessions    map[string]*memSession
+	resets      map[string]*memPasswordReset
+	uploadLinks map[string]*UploadLink
+	pending     map[string]*memPendingDeletion
+	deletions   []memAccountDeletion
+}
+
+type memSession struct {
+	id, userID          string
+	tokenHash, prevHash string
+	expiresAt           time.Time
+	revoked             bool
+}
+
+type memPasswordReset struct {
+	userID    string
+	expiresAt time.Time
+	used      bool
+}
+
+type memPendingDeletion struct {
+	attempts  int
+	lastErr   string
+	updatedAt time.Time
+}
+
+type memAccountDeletion struct {
+	userID string
+	report []byte
+}
+
+// NewMemory returns repositories that keep all data in memory. They behave
+// like the Postgres repositories and are meant for tests and local runs.
+func NewMemory() Repos {
+	s := &memStore{
+		users:       map[string]User{},
+		folders:     map[string]Folder{},
+		images:      map[string]Image{},
+		variants:    map[string][]ImageVariant{},
+		sessions:    map[string]*memSession{},
+		resets:      map[string]*memPasswordReset{},
+		uploadLinks: map[string]*UploadLink{},
+		pending:     map[string]*memPendingDeletion{},
+	}
+	return Repos{
+		Users:          memUsers{s},
+		Folders:        memFolders{s},
+		Images:         memImages{s},
+		Sessions:       memSessions{s},
+		PasswordResets: memPasswordResets{s},
+		UploadLinks:    memUploadLinks{s},
+		Assets:         memAssets{s},
+	}
+}
+
+// unreferencedKeys mirrors the Postgres helper; the caller holds the lock.
+func (s *memStore) unreferencedKeys(keys []string) []string {
+	used := map[string]bool{}
+	for id, img := range s.images {
+		used[img.StorageKey] = true
+		for _, v := range s.variants[id] {
+			used[v.StorageKey] = true
+		}
+	}
+	var out []string
+	for _, k := range uniqueStrings(keys) {
+		if k != "" && !used[k] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// withVariants returns a copy of img with its variant map attached; the caller holds the lock.
+func (s *memStore) withVariants(img Image) Image {
+	img.Variants = withOriginal(img, s.variants[img.ID])
+	return img
+}
+
+// deleteImage removes an image and returns its storage keys; the caller holds the lock.
+func (s *memStore) deleteImage(id string) []string {
+	img := s.images[id]
+	keys := []string{img.StorageKey}
+	for _, v := range s.variants[id] {
+		keys = append(keys, v.StorageKey)
+	}
+	delete(s.images, id)
+	delete(s.variants, id)
+	return keys
+}
+
+type memUsers struct{ s *memStore }
+
+func (r memUsers) Create(ctx context.Context, u User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.users {
+		if existing.Email == u.Email {
+			return ErrConflict
+		}
+	}
+	if u.ID == "" {
+		u.ID = uuid.NewString()
+	}
+	if u.Role == "" {
+		u.Role = "user"
+	}
+	u.CreatedAt = time.Now()
+	r.s.users[u.ID] = u
+	return nil
+}
+
+func (r memUsers) GetByID(ctx context.Context, id string) (User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (r memUsers) GetByEmail(ctx context.Context, email string) (User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, u := range r.s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (r memUsers) UpdateProfile(ctx context.Context, id, firstName, lastName string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if u, ok := r.s.users[id]; ok {
+		u.FirstName, u.LastName = firstName, lastName
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r memUsers) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if u, ok := r.s.users[id]; ok {
+		u.PasswordHash = passwordHash
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r memUsers) SetQRCode(ctx context.Context, id, link, key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if u, ok := r.s.users[id]; ok {
+		u.QRCodeLink, u.QRCodeKey = link, key
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r memUsers) ListLegacyQRCodes(ctx context.Context, afterID string, limit int) ([]User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	hasLink := map[string]bool{}
+	for _, l := range r.s.uploadLinks {
+		hasLink[l.UserID] = true
+	}
+	var users []User
+	for _, u := range r.s.users {
+		if u.QRCodeLink != "" && u.ID > afterID && !hasLink[u.ID] {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r memUsers) MarkEmailVerified(ctx context.Context, id, email string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok || u.Email != email {
+		return false, ErrNotFound
+	}
+	if u.EmailVerifiedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	r.s.users[id] = u
+	return true, nil
+}
+
+func (r memUsers) Delete(ctx context.Context, id string) error {
+	_, err := r.Purge(ctx, id)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (r memUsers) Purge(ctx context.Context, id string) (PurgeResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var res PurgeResult
+	u, ok := r.s.users[id]
+	if !ok {
+		return res, ErrNotFound
+	}
+	res.QRCodeKey = u.QRCodeKey
+
+	var keys []string
+	for imgID, img := range r.s.images {
+		if img.UserID == id {
+			keys = append(keys, r.s.deleteImage(imgID)...)
+			res.ImagesDeleted++
+		}
+	}
+	for folderID, f := range r.s.folders {
+		if f.UserID == id {
+			delete(r.s.folders, folderID)
+			res.FoldersDeleted++
+		}
+	}
+	for sessionID, sess := range r.s.sessions {
+		if sess.userID == id {
+			delete(r.s.sessions, sessionID)
+		}
+	}
+	for linkID, l := range r.s.uploadLinks {
+		if l.UserID == id {
+			delete(r.s.uploadLinks, linkID)
+		}
+	}
+	for hash, reset := range r.s.resets {
+		if reset.userID == id {
+			delete(r.s.resets, hash)
+		}
+	}
+	delete(r.s.users, id)
+
+	res.AssetKeys = r.s.unreferencedKeys(keys)
+	return res, nil
+}
+
+type memFolders struct{ s *memStore }
+
+func (r memFolders) Create(ctx context.Context, f Folder) (Folder, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if f.ID == "" {
+		f.ID = uuid.NewString()
+	}
+	f.CreatedAt = time.Now()
+	f.UpdatedAt = f.CreatedAt
+	r.s.folders[f.ID] = f
+	return f, nil
+}
+
+func (r memFolders) List(ctx context.Context, q FolderQuery) ([]Folder, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	name := strings.ToLower(q.Name)
+	folders := []Folder{}
+	for _, f := range r.s.folders {
+		if f.UserID != q.UserID || !strings.Contains(strings.ToLower(f.Name), name) {
+			continue
+		}
+		folders = append(folders, f)
+	}
+
+	less := func(a, b Folder) int {
+		if q.Sort == SortName {
+			return strings.Compare(a.Name, b.Name)
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	folders = sortAndPage(folders, less, func(f Folder) string { return f.ID }, q.Desc, q.Limit,
+		q.After, func(c Cursor) (Folder, bool) {
+			if q.Sort == SortName {
+				return Folder{ID: c.ID, Name: c.Value}, true
+			}
+			t, err := time.Parse(time.RFC3339Nano, c.Value)
+			return Folder{ID: c.ID, CreatedAt: t}, err == nil
+		})
+	return folders, nil
+}
+
+func (r memFolders) BelongsTo(ctx context.Context, id, userID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	f, ok := r.s.folders[id]
+	return ok && f.UserID == userID, nil
+}
+
+func (r memFolders) Delete(ctx context.Context, id, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	f, ok := r.s.folders[id]
+	if !ok || f.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.s.folders, id)
+
+	// ON DELETE SET NULL for images and upload links
+	for imgID, img := range r.s.images {
+		if img.FolderID != nil && *img.FolderID == id {
+			img.FolderID = nil
+			r.s.images[imgID] = img
+		}
+	}
+	for _, l := range r.s.uploadLinks {
+		if l.FolderID != nil && *l.FolderID == id {
+			l.FolderID = nil
+		}
+	}
+	return nil
+}
+
+type memImages struct{ s *memStore }
+
+func (r memImages) Create(ctx context.Context, img Image, variants []ImageVariant) (Image, error) {
+	// device_info is a JSONB column, which refuses anything else
+	if len(img.DeviceInfo) > 0 && !json.Valid(img.DeviceInfo) {
+		return Image{}, errors.New("device_info is not valid JSON")
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	img.ID = uuid.NewString()
+	img.CreatedAt = time.Now()
+	img.Variants = nil
+	r.s.images[img.ID] = img
+	r.s.variants[img.ID] = append([]ImageVariant(nil), variants...)
+	return r.s.withVariants(img), nil
+}
+
+func (r memImages) List(ctx context.Context, q ImageQuery) ([]Image, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	device := strings.ToLower(q.Device)
+	images := []Image{}
+	for _, img := range r.s.images {
+		switch {
+		case img.UserID != q.UserID:
+			continue
+		case q.Unfiled && img.FolderID != nil:
+			continue
+		case q.FolderID != "" && (img.FolderID == nil || *img.FolderID != q.FolderID):
+			continue
+		case q.UploadedFrom != nil && img.CreatedAt.Before(*q.UploadedFrom):
+			continue
+		case q.UploadedTo != nil && !img.CreatedAt.Before(*q.UploadedTo):
+			continue
+		case q.CapturedFrom != nil && (img.CapturedAt == nil || img.CapturedAt.Before(*q.CapturedFrom)):
+			continue
+		case q.CapturedTo != nil && (img.CapturedAt == nil || !img.CapturedAt.Before(*q.CapturedTo)):
+			continue
+		case device != "" && !strings.Contains(strings.ToLower(string(img.DeviceInfo)), device):
+			continue
+		case q.UploadLinkID != "" && (img.UploadLinkID == nil || *img.UploadLinkID != q.UploadLinkID):
+			continue
+		}
+		images = append(images, r.s.withVariants(img))
+	}
+
+	less := func(a, b Image) int {
+		switch q.Sort {
+		case SortCaptured:
+			return capturedOrUploaded(a).Compare(capturedOrUploaded(b))
+		case SortSize:
+			return compareInt64(sizeOf(a), sizeOf(b))
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+	images = sortAndPage(images, less, func(img Image) string { return img.ID }, q.Desc, q.Limit,
+		q.After, func(c Cursor) (Image, bool) {
+			switch q.Sort {
+			case SortCaptured:
+				t, err := time.Parse(wallClockLayout, c.Value)
+				return Image{ID: c.ID, CapturedAt: &t}, err == nil
+			case SortSize:
+				n, err := strconv.ParseInt(c.Value, 10, 64)
+				return Image{ID: c.ID, SizeBytes: &n}, err == nil
+			default:
+				t, err := time.Parse(time.RFC3339Nano, c.Value)
+				return Image{ID: c.ID, CreatedAt: t}, err == nil
+			}
+		})
+	return images, nil
+}
+
+func (r memImages) Delete(ctx context.Context, id, userID string) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	img, ok := r.s.images[id]
+	if !ok || img.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return r.s.unreferencedKeys(r.s.deleteImage(id)), nil
+}
+
+func (r memImages) DeleteWithToken(ctx context.Context, id, tokenHash string) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	img, ok := r.s.images[id]
+	if !ok || img.DeletionTokenHash == "" || img.DeletionTokenHash != tokenHash {
+		return nil, ErrNotFound
+	}
+	return r.s.unreferencedKeys(r.s.deleteImage(id)), nil
+}
+
+func (r memImages) Move(ctx context.Context, userID string, ids []string, folderID string) ([]string, error) {
+	return r.reassign(userID, ids, folderID, func(img Image) string {
+		r.s.images[img.ID] = img
+		return img.ID
+	})
+}
+
+func (r memImages) Copy(ctx context.Context, userID string, ids []string, folderID string) ([]string, error) {
+	return r.reassign(userID, ids, folderID, func(img Image) string {
+		sourceID := img.ID
+		img.ID = uuid.NewString()
+		img.CreatedAt = time.Now()
+		img.DeletionTokenHash = ""
+		r.s.images[img.ID] = img
+		r.s.variants[img.ID] = append([]ImageVariant(nil), r.s.variants[sourceID]...)
+		return img.ID
+	})
+}
+
+func (r memImages) reassign(userID string, ids []string, folderID string, apply func(Image) string) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ids = uniqueStrings(ids)
+	for _, id := range ids {
+		if img, ok := r.s.images[id]; !ok || img.UserID != userID {
+			return nil, ErrNotFound
+		}
+	}
+
+	var folder *string
+	if folderID != "" {
+		folder = &folderID
+	}
+	out := []string{}
+	for _, id := range ids {
+		img := r.s.images[id]
+		img.FolderID = folder
+		out = append(out, apply(img))
+	}
+	return out, nil
+}
+
+type memSessions struct{ s *memStore }
+
+func (r memSessions) Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id := uuid.NewString()
+	r.s.sessions[id] = &memSession{id: id, userID: userID, tokenHash: tokenHash, expiresAt: expiresAt}
+	return id, nil
+}
+
+func (r memSessions) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (string, string, string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	for _, sess := range r.s.sessions {
+		if sess.tokenHash == oldHash && !sess.revoked && sess.expiresAt.After(now) {
+			u, ok := r.s.users[sess.userID]
+			if !ok {
+				break
+			}
+			sess.prevHash, sess.tokenHash, sess.expiresAt = sess.tokenHash, newHash, expiresAt
+			return sess.userID, u.Email, sess.id, nil
+		}
+	}
+
+	// A rotated-out token being replayed: revoke the session it belonged to
+	for _, sess := range r.s.sessions {
+		if sess.prevHash == oldHash {
+			sess.revoked = true
+		}
+	}
+	return "", "", "", ErrNotFound
+}
+
+func (r memSessions) Active(ctx context.Context, id, userID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sess, ok := r.s.sessions[id]
+	return ok && sess.userID == userID && !sess.revoked && sess.expiresAt.After(time.Now()), nil
+}
+
+func (r memSessions) Revoke(ctx context.Context, id, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if sess, ok := r.s.sessions[id]; ok && sess.userID == userID {
+		sess.revoked = true
+	}
+	return nil
+}
+
+func (r memSessions) RevokeAll(ctx context.Context, userID, exceptID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, sess := range r.s.sessions {
+		if sess.userID == userID && id != exceptID {
+			sess.revoked = true
+		}
+	}
+	return nil
+}
+
+type memPasswordResets struct{ s *memStore }
+
+func (r memPasswordResets) Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.resets[tokenHash] = &memPasswordReset{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r memPasswordResets) Redeem(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	reset, ok := r.s.resets[tokenHash]
+	if !ok || reset.used || !reset.expiresAt.After(time.Now()) {
+		return "", ErrNotFound
+	}
+	u, ok := r.s.users[reset.userID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	r.s.users[u.ID] = u
+	r.s.invalidateResets(u.ID)
+	return u.ID, nil
+}
+
+func (r memPasswordResets) InvalidateAll(ctx context.Context, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.invalidateResets(userID)
+	return nil
+}
+
+// invalidateResets marks userID's reset tokens used; the caller holds the lock.
+func (s *memStore) invalidateResets(userID string) {
+	for _, reset := range s.resets {
+		if reset.userID == userID {
+			reset.used = true
+		}
+	}
+}
+
+type memUploadLinks struct{ s *memStore }
+
+func (r memUploadLinks) Create(ctx context.Context, l UploadLink) (UploadLink, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	l.ID = uuid.NewString()
+	l.CreatedAt = time.Now()
+	r.s.uploadLinks[l.ID] = &l
+	return l, nil
+}
+
+func (r memUploadLinks) ListByUser(ctx context.Context, userID string) ([]UploadLink, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	links := []UploadLink{}
+	for _, l := range r.s.uploadLinks {
+		if l.UserID == userID {
+			links = append(links, *l)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.After(links[j].CreatedAt) })
+	return links, nil
+}
+
+func (r memUploadLinks) GetActive(ctx context.Context, tokenHash string) (UploadLink, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, l := range r.s.uploadLinks {
+		if l.TokenHash == tokenHash && l.Active(time.Now()) {
+			return *l, nil
+		}
+	}
+	return UploadLink{}, ErrNotFound
+}
+
+func (r memUploadLinks) Revoke(ctx context.Context, id, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	l, ok := r.s.uploadLinks[id]
+	if !ok || l.UserID != userID {
+		return ErrNotFound
+	}
+	if l.RevokedAt == nil {
+		now := time.Now()
+		l.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r memUploadLinks) Reserve(ctx context.Context, id string, size int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	l, ok := r.s.uploadLinks[id]
+	if !ok ||
+		(l.MaxFiles != nil && l.FilesUploaded >= *l.MaxFiles) ||
+		(l.MaxBytes != nil && l.BytesUploaded+size > *l.MaxBytes) {
+		return ErrConflict
+	}
+	l.FilesUploaded++
+	l.BytesUploaded += size
+	return nil
+}
+
+func (r memUploadLinks) AddBytes(ctx context.Context, id string, size int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	l, ok := r.s.uploadLinks[id]
+	if !ok || (l.MaxBytes != nil && l.BytesUploaded+size > *l.MaxBytes) {
+		return ErrConflict
+	}
+	l.BytesUploaded += size
+	return nil
+}
+
+func (r memUploadLinks) Release(ctx context.Context, id string, size int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if l, ok := r.s.uploadLinks[id]; ok {
+		l.FilesUploaded = max(l.FilesUploaded-1, 0)
+		l.BytesUploaded = max(l.BytesUploaded-size, 0)
+	}
+	return nil
+}
+
+type memAssets struct{ s *memStore }
+
+func (r memAssets) QueueDeletion(ctx context.Context, key string, attempts int, lastErr string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	p, ok := r.s.pending[key]
+	if !ok {
+		p = &memPendingDeletion{}
+		r.s.pending[key] = p
+	}
+	p.attempts += attempts
+	p.lastErr = lastErr
+	p.updatedAt = time.Now()
+	return nil
+}
+
+func (r memAssets) PendingDeletions(ctx context.Context, limit int) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	keys := make([]string, 0, len(r.s.pending))
+	for key := range r.s.pending {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return r.s.pending[keys[i]].updatedAt.Before(r.s.pending[keys[j]].updatedAt)
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+func (r memAssets) DeletionFailed(ctx context.Context, key string, lastErr string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if p, ok := r.s.pending[key]; ok {
+		p.attempts++
+		p.lastErr = lastErr
+		p.updatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r memAssets) DeletionDone(ctx context.Context, key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.pending, key)
+	return nil
+}
+
+func (r memAssets) RecordAccountDeletion(ctx context.Context, userID string, report []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.deletions = append(r.s.deletions, memAccountDeletion{userID: userID, report: append([]byte(nil), report...)})
+	return nil
+}
+
+// sortAndPage orders items by cmp with id as tie-breaker, skips everything up
+// to and including the cursor position and applies limit (0 for no limit).
+func sortAndPage[T any](items []T, cmp func(a, b T) int, id func(T) string, desc bool, limit int,
+	after *Cursor, fromCursor func(Cursor) (T, bool)) []T {
+	order := func(a, b T) int {
+		c := cmp(a, b)
+		if c == 0 {
+			c = strings.Compare(id(a), id(b))
+		}
+		if desc {
+			c = -c
+		}
+		return c
+	}
+	sort.Slice(items, func(i, j int) bool { return order(items[i], items[j]) < 0 })
+
+	if after != nil {
+		pivot, ok := fromCursor(*after)
+		if !ok {
+			return []T{}
+		}
+		i := sort.Search(len(items), func(i int) bool { return order(items[i], pivot) > 0 })
+		items = items[i:]
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func sizeOf(img Image) int64 {
+	if img.SizeBytes == nil {
+		return 0
+	}
+	return *img.SizeBytes
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMemoryImagesRejectInvalidDeviceInfo(t *testing.T) {
+	repos := NewMemory()
+	ctx := context.Background()
+
+	if _, err := repos.Images.Create(ctx, Image{UserID: "u", DeviceInfo: json.RawMessage("not json")}, nil); err == nil {
+		t.Fatal("Create accepted device info that is not JSON")
+	}
+	for _, info := range []string{"", `{"browser":"test"}`} {
+		if _, err := repos.Images.Create(ctx, Image{UserID: "u", DeviceInfo: json.RawMessage(info)}, nil); err != nil {
+			t.Fatalf("Create with device info %q: %v", info, err)
+		}
+	}
+}
@@ -0,0 +1,848 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NewPostgres returns repositories backed by a PostgreSQL database migrated
+// with the schema in internal/db/migrations.
+func NewPostgres(db *sql.DB) Repos {
+	return Repos{
+		Users:          &pgUsers{db: db},
+		Folders:        &pgFolders{db: db},
+		Images:         &pgImages{db: db},
+		Sessions:       &pgSessions{db: db},
+		PasswordResets: &pgPasswordResets{db: db},
+		UploadLinks:    &pgUploadLinks{db: db},
+		Assets:         &pgAssets{db: db},
+	}
+}
+
+// validUUID guards id parameters; Postgres rejects malformed UUIDs with an
+// error, which callers should see as a plain "not found".
+func validUUID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// unreferencedKeys filters keys down to those no image or variant row uses.
+func unreferencedKeys(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT k FROM unnest($1::text[]) AS k
+		WHERE NOT EXISTS (SELECT 1 FROM images WHERE storage_key = k)
+		  AND NOT EXISTS (SELECT 1 FROM image_variants WHERE storage_key = k)
+	`, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+type pgUsers struct {
+	db *sql.DB
+}
+
+const userColumns = `id, first_name, last_name, email, password, role, qr_code_link, COALESCE(qr_code_key, ''), email_verified_at, created_at`
+
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.PasswordHash, &u.Role, &u.QRCodeLink, &u.QRCodeKey, &u.EmailVerifiedAt, &u.CreatedAt)
+	return u, notFound(err)
+}
+
+func (r *pgUsers) Create(ctx context.Context, u User) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (id, first_name, last_name, email, password, qr_code_link, qr_code_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, u.ID, u.FirstName, u.LastName, u.Email, u.PasswordHash, u.QRCodeLink, u.QRCodeKey)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *pgUsers) GetByID(ctx context.Context, id string) (User, error) {
+	if !validUUID(id) {
+		return User{}, ErrNotFound
+	}
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+}
+
+func (r *pgUsers) GetByEmail(ctx context.Context, email string) (User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email))
+}
+
+func (r *pgUsers) UpdateProfile(ctx context.Context, id, firstName, lastName string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE users SET "first_name" = $1, "last_name" = $2, updated_at = NOW() WHERE id = $3
+	`, firstName, lastName, id)
+	return err
+}
+
+func (r *pgUsers) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`, passwordHash, id)
+	return err
+}
+
+func (r *pgUsers) SetQRCode(ctx context.Context, id, link, key string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET qr_code_link = $1, qr_code_key = $2, updated_at = NOW() WHERE id = $3`, link, key, id)
+	return err
+}
+
+func (r *pgUsers) ListLegacyQRCodes(ctx context.Context, afterID string, limit int) ([]User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userColumns+` FROM users u
+		WHERE qr_code_link <> ''
+		  AND ($1 = '' OR id::text > $1)
+		  AND NOT EXISTS (SELECT 1 FROM upload_links l WHERE l.user_id = u.id)
+		ORDER BY id::text
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *pgUsers) MarkEmailVerified(ctx context.Context, id, email string) (bool, error) {
+	if !validUUID(id) {
+		return false, ErrNotFound
+	}
+	var newly bool
+	err := r.db.QueryRowContext(ctx, `
+		WITH target AS (
+			SELECT id, email_verified_at FROM users WHERE id = $1 AND email = $2 FOR UPDATE
+		), updated AS (
+			UPDATE users u SET email_verified_at = NOW(), updated_at = NOW()
+			FROM target t
+			WHERE u.id = t.id AND t.email_verified_at IS NULL
+			RETURNING u.id
+		)
+		SELECT EXISTS (SELECT 1 FROM updated) FROM target
+	`, id, email).Scan(&newly)
+	return newly, notFound(err)
+}
+
+func (r *pgUsers) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	return err
+}
+
+func (r *pgUsers) Purge(ctx context.Context, id string) (PurgeResult, error) {
+	var res PurgeResult
+	if !validUUID(id) {
+		return res, ErrNotFound
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	var qrKey sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT qr_code_key FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&qrKey)
+	if err != nil {
+		return res, notFound(err)
+	}
+	res.QRCodeKey = qrKey.String
+
+	var keys []string
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(DISTINCT storage_key) FILTER (WHERE storage_key IS NOT NULL), '{}')
+		FROM (
+			SELECT storage_key FROM images WHERE user_id = $1
+			UNION
+			SELECT v.storage_key FROM image_variants v JOIN images i ON i.id = v.image_id WHERE i.user_id = $1
+		) keys
+	`, id).Scan(pq.Array(&keys))
+	if err != nil {
+		return res, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM images WHERE user_id = $1`, id)
+	if err != nil {
+		return res, err
+	}
+	n, _ := result.RowsAffected()
+	res.ImagesDeleted = int(n)
+
+	result, err = tx.ExecContext(ctx, `DELETE FROM folders WHERE user_id = $1`, id)
+	if err != nil {
+		return res, err
+	}
+	n, _ = result.RowsAffected()
+	res.FoldersDeleted = int(n)
+
+	// Sessions and upload links go with the user through ON DELETE CASCADE
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return res, err
+	}
+
+	res.AssetKeys, err = unreferencedKeys(ctx, tx, keys)
+	if err != nil {
+		return res, err
+	}
+	return res, tx.Commit()
+}
+
+type pgFolders struct {
+	db *sql.DB
+}
+
+func (r *pgFolders) Create(ctx context.Context, f Folder) (Folder, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO folders (id, user_id, name) VALUES ($1, $2, $3)
+		RETURNING created_at, updated_at
+	`, f.ID, f.UserID, f.Name).Scan(&f.CreatedAt, &f.UpdatedAt)
+	return f, err
+}
+
+func (r *pgFolders) List(ctx context.Context, q FolderQuery) ([]Folder, error) {
+	b := newQueryBuilder("user_id = $1", q.UserID)
+	if q.Name != "" {
+		b.where("name ILIKE " + b.arg("%"+escapeLike(q.Name)+"%"))
+	}
+
+	sortExpr, sortCast := "created_at", "timestamptz"
+	if q.Sort == SortName {
+		sortExpr, sortCast = "name", "text"
+	}
+	if q.After != nil {
+		if !validUUID(q.After.ID) {
+			return nil, ErrNotFound
+		}
+		b.keyset(sortExpr, sortCast, q.Desc, *q.After)
+	}
+
+	rows, err := r.db.QueryContext(ctx, b.query("SELECT id, user_id, name, created_at, updated_at FROM folders", sortExpr, q.Desc, q.Limit), b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []Folder{}
+	for rows.Next() {
+		var f Folder
+		if err := rows.Scan(&f.ID, &f.UserID, &f.Name, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+func (r *pgFolders) BelongsTo(ctx context.Context, id, userID string) (bool, error) {
+	if !validUUID(id) {
+		return false, nil
+	}
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM folders WHERE id = $1 AND user_id = $2)", id, userID).Scan(&exists)
+	return exists, err
+}
+
+func (r *pgFolders) Delete(ctx context.Context, id, userID string) error {
+	if !validUUID(id) {
+		return ErrNotFound
+	}
+	res, err := r.db.ExecContext(ctx, "DELETE FROM folders WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type pgImages struct {
+	db *sql.DB
+}
+
+const imageColumns = `id, user_id, image_url, folder_id, width, height, size_bytes,
+	captured_at, camera_make, camera_model, gps_latitude, gps_longitude, created_at`
+
+var imageSortExprs = map[string]struct{ expr, cast string }{
+	SortUploaded: {"created_at", "timestamptz"},
+	SortCaptured: {"COALESCE(captured_at, created_at AT TIME ZONE 'UTC')", "timestamp"},
+	SortSize:     {"COALESCE(size_bytes, 0)", "bigint"},
+}
+
+func (r *pgImages) Create(ctx context.Context, img Image, variants []ImageVariant) (Image, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return img, err
+	}
+	defer tx.Rollback()
+
+	var deviceInfo any
+	if len(img.DeviceInfo) > 0 {
+		deviceInfo = string(img.DeviceInfo)
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO images (user_id, image_url, storage_key, device_info, folder_id, deletion_token_hash, upload_link_id, width, height, size_bytes,
+		                    captured_at, camera_make, camera_model, orientation, gps_latitude, gps_longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at
+	`, img.UserID, img.URL, img.StorageKey, deviceInfo, img.FolderID, img.DeletionTokenHash, img.UploadLinkID,
+		img.Width, img.Height, img.SizeBytes,
+		img.CapturedAt, img.CameraMake, img.CameraModel, img.Orientation, img.GPSLatitude, img.GPSLongitude).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return img, err
+	}
+
+	for _, v := range variants {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO image_variants (image_id, variant, storage_key, url, width, height, size_bytes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, img.ID, v.Name, v.StorageKey, v.URL, v.Width, v.Height, v.SizeBytes)
+		if err != nil {
+			return img, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return img, err
+	}
+	img.Variants = withOriginal(img, variants)
+	return img, nil
+}
+
+func (r *pgImages) List(ctx context.Context, q ImageQuery) ([]Image, error) {
+	b := newQueryBuilder("user_id = $1", q.UserID)
+
+	switch {
+	case q.Unfiled:
+		b.where("folder_id IS NULL")
+	case q.FolderID != "":
+		if !validUUID(q.FolderID) {
+			return []Image{}, nil
+		}
+		b.where("folder_id = " + b.arg(q.FolderID))
+	}
+	if q.UploadedFrom != nil {
+		b.where("created_at >= " + b.arg(*q.UploadedFrom))
+	}
+	if q.UploadedTo != nil {
+		b.where("created_at < " + b.arg(*q.UploadedTo))
+	}
+	if q.CapturedFrom != nil {
+		b.where("captured_at >= " + b.arg(q.CapturedFrom.Format(wallClockLayout)) + "::timestamp")
+	}
+	if q.CapturedTo != nil {
+		b.where("captured_at < " + b.arg(q.CapturedTo.Format(wallClockLayout)) + "::timestamp")
+	}
+	if q.Device != "" {
+		b.where("device_info::text ILIKE " + b.arg("%"+escapeLike(q.Device)+"%"))
+	}
+	if q.UploadLinkID != "" {
+		if !validUUID(q.UploadLinkID) {
+			return []Image{}, nil
+		}
+		b.where("upload_link_id = " + b.arg(q.UploadLinkID))
+	}
+
+	sort, ok := imageSortExprs[q.Sort]
+	if !ok {
+		sort = imageSortExprs[SortUploaded]
+	}
+	if q.After != nil {
+		if !validUUID(q.After.ID) {
+			return nil, ErrNotFound
+		}
+		b.keyset(sort.expr, sort.cast, q.Desc, *q.After)
+	}
+
+	return r.query(ctx, b.query("SELECT "+imageColumns+" FROM images", sort.expr, q.Desc, q.Limit), b.args...)
+}
+
+// query runs an image query selecting imageColumns and attaches each image's variants.
+func (r *pgImages) query(ctx context.Context, query string, args ...any) ([]Image, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []Image{}
+	var ids []string
+	byID := map[string]int{}
+	for rows.Next() {
+		var img Image
+		if err := rows.Scan(&img.ID, &img.UserID, &img.URL, &img.FolderID, &img.Width, &img.Height, &img.SizeBytes,
+			&img.CapturedAt, &img.CameraMake, &img.CameraModel, &img.GPSLatitude, &img.GPSLongitude, &img.CreatedAt); err != nil {
+			return nil, err
+		}
+		img.Variants = withOriginal(img, nil)
+		byID[img.ID] = len(images)
+		ids = append(ids, img.ID)
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return images, nil
+	}
+
+	vrows, err := r.db.QueryContext(ctx, `
+		SELECT image_id, variant, url, width, height FROM image_variants WHERE image_id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer vrows.Close()
+
+	for vrows.Next() {
+		var imageID string
+		var v ImageVariant
+		if err := vrows.Scan(&imageID, &v.Name, &v.URL, &v.Width, &v.Height); err != nil {
+			return nil, err
+		}
+		images[byID[imageID]].Variants[v.Name] = v
+	}
+	return images, vrows.Err()
+}
+
+func (r *pgImages) Delete(ctx context.Context, id, userID string) ([]string, error) {
+	return r.delete(ctx, "user_id = $2", id, userID)
+}
+
+func (r *pgImages) DeleteWithToken(ctx context.Context, id, tokenHash string) ([]string, error) {
+	return r.delete(ctx, "deletion_token_hash = $2", id, tokenHash)
+}
+
+func (r *pgImages) delete(ctx context.Context, scope, id, scopeArg string) ([]string, error) {
+	if !validUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Variant rows are removed by the cascade but still visible to the outer SELECT
+	var storageKey sql.NullString
+	var keys []string
+	err = tx.QueryRowContext(ctx, `
+		WITH deleted AS (DELETE FROM images WHERE id = $1 AND `+scope+` RETURNING id, storage_key)
+		SELECT deleted.storage_key,
+		       ARRAY(SELECT storage_key FROM image_variants WHERE image_id = deleted.id)
+		FROM deleted
+	`, id, scopeArg).Scan(&storageKey, pq.Array(&keys))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if storageKey.Valid {
+		keys = append(keys, storageKey.String)
+	}
+
+	// Copies share their source's assets, so only return keys nothing else uses
+	keys, err = unreferencedKeys(ctx, tx, keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, tx.Commit()
+}
+
+func (r *pgImages) Move(ctx context.Context, userID string, ids []string, folderID string) ([]string, error) {
+	return r.reassign(ctx, userID, ids, folderID, `
+		UPDATE images SET folder_id = $3, updated_at = NOW()
+		WHERE id = ANY($1) AND user_id = $2
+		RETURNING id
+	`)
+}
+
+func (r *pgImages) Copy(ctx context.Context, userID string, ids []string, folderID string) ([]string, error) {
+	// Copies reference the same assets as their source, variants included
+	return r.reassign(ctx, userID, ids, folderID, `
+		WITH sources AS (
+			SELECT id AS source_id, gen_random_uuid() AS new_id
+			FROM images WHERE id = ANY($1) AND user_id = $2
+		), copied AS (
+			INSERT INTO images (id, user_id, image_url, storage_key, device_info, folder_id, upload_link_id, width, height, size_bytes,
+			                    captured_at, camera_make, camera_model, orientation, gps_latitude, gps_longitude)
+			SELECT s.new_id, i.user_id, i.image_url, i.storage_key, i.device_info, $3, i.upload_link_id, i.width, i.height, i.size_bytes,
+			       i.captured_at, i.camera_make, i.camera_model, i.orientation, i.gps_latitude, i.gps_longitude
+			FROM images i JOIN sources s ON s.source_id = i.id
+			RETURNING id
+		), variants AS (
+			INSERT INTO image_variants (image_id, variant, storage_key, url, width, height, size_bytes)
+			SELECT s.new_id, v.variant, v.storage_key, v.url, v.width, v.height, v.size_bytes
+			FROM image_variants v JOIN sources s ON s.source_id = v.image_id
+		)
+		SELECT id FROM copied
+	`)
+}
+
+func (r *pgImages) reassign(ctx context.Context, userID string, ids []string, folderID, stmt string) ([]string, error) {
+	for _, id := range ids {
+		if !validUUID(id) {
+			return nil, ErrNotFound
+		}
+	}
+	var folder sql.NullString
+	if folderID != "" {
+		folder = sql.NullString{String: folderID, Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Every image must belong to the user, otherwise nothing is changed
+	var owned int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM images WHERE id = ANY($1) AND user_id = $2", pq.Array(ids), userID).Scan(&owned)
+	if err != nil {
+		return nil, err
+	}
+	if owned != len(uniqueStrings(ids)) {
+		return nil, ErrNotFound
+	}
+
+	rows, err := tx.QueryContext(ctx, stmt, pq.Array(ids), userID, folder)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+type pgSessions struct {
+	db *sql.DB
+}
+
+func (r *pgSessions) Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sessions (user_id, refresh_token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, userID, tokenHash, expiresAt).Scan(&id)
+	return id, err
+}
+
+func (r *pgSessions) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (userID, email, sessionID string, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE sessions s
+		SET refresh_token_hash = $2, previous_token_hash = s.refresh_token_hash,
+		    expires_at = $3, last_used_at = NOW()
+		FROM users u
+		WHERE s.user_id = u.id
+		  AND s.refresh_token_hash = $1
+		  AND s.revoked_at IS NULL
+		  AND s.expires_at > NOW()
+		RETURNING s.user_id, u.email, s.id
+	`, oldHash, newHash, expiresAt).Scan(&userID, &email, &sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A rotated-out token being replayed: revoke the session it belonged to
+		_, _ = r.db.ExecContext(ctx, `
+			UPDATE sessions SET revoked_at = NOW()
+			WHERE previous_token_hash = $1 AND revoked_at IS NULL
+		`, oldHash)
+		return "", "", "", ErrNotFound
+	}
+	return userID, email, sessionID, err
+}
+
+func (r *pgSessions) Active(ctx context.Context, id, userID string) (bool, error) {
+	if !validUUID(id) {
+		return false, nil
+	}
+	var active bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`, id, userID).Scan(&active)
+	return active, err
+}
+
+func (r *pgSessions) Revoke(ctx context.Context, id, userID string) error {
+	if !validUUID(id) {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	return err
+}
+
+func (r *pgSessions) RevokeAll(ctx context.Context, userID, exceptID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND id::text <> $2
+	`, userID, exceptID)
+	return err
+}
+
+type pgPasswordResets struct {
+	db *sql.DB
+}
+
+func (r *pgPasswordResets) Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	return err
+}
+
+func (r *pgPasswordResets) Redeem(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Claiming the row first means two concurrent redemptions can't both succeed
+	var userID string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		return "", notFound(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`, passwordHash, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return "", err
+	}
+	return userID, tx.Commit()
+}
+
+func (r *pgPasswordResets) InvalidateAll(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	return err
+}
+
+type pgUploadLinks struct {
+	db *sql.DB
+}
+
+const uploadLinkColumns = `id, user_id, folder_id, name, starts_at, ends_at, max_files, max_bytes,
+	files_uploaded, bytes_uploaded, revoked_at, created_at`
+
+func scanUploadLink(row interface{ Scan(...any) error }) (UploadLink, error) {
+	var l UploadLink
+	err := row.Scan(&l.ID, &l.UserID, &l.FolderID, &l.Name, &l.StartsAt, &l.EndsAt, &l.MaxFiles, &l.MaxBytes,
+		&l.FilesUploaded, &l.BytesUploaded, &l.RevokedAt, &l.CreatedAt)
+	return l, notFound(err)
+}
+
+func (r *pgUploadLinks) Create(ctx context.Context, l UploadLink) (UploadLink, error) {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO upload_links (user_id, folder_id, name, token_hash, starts_at, ends_at, max_files, max_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+uploadLinkColumns,
+		l.UserID, l.FolderID, l.Name, l.TokenHash, l.StartsAt, l.EndsAt, l.MaxFiles, l.MaxBytes)
+	return scanUploadLink(row)
+}
+
+func (r *pgUploadLinks) ListByUser(ctx context.Context, userID string) ([]UploadLink, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+uploadLinkColumns+` FROM upload_links WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []UploadLink{}
+	for rows.Next() {
+		link, err := scanUploadLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *pgUploadLinks) GetActive(ctx context.Context, tokenHash string) (UploadLink, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+uploadLinkColumns+`
+		FROM upload_links
+		WHERE token_hash = $1
+		  AND revoked_at IS NULL
+		  AND (starts_at IS NULL OR starts_at <= NOW())
+		  AND (ends_at IS NULL OR ends_at > NOW())
+	`, tokenHash)
+	return scanUploadLink(row)
+}
+
+func (r *pgUploadLinks) Revoke(ctx context.Context, id, userID string) error {
+	if !validUUID(id) {
+		return ErrNotFound
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE upload_links SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *pgUploadLinks) Reserve(ctx context.Context, id string, size int64) error {
+	var got string
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE upload_links
+		SET files_uploaded = files_uploaded + 1, bytes_uploaded = bytes_uploaded + $2
+		WHERE id = $1
+		  AND (max_files IS NULL OR files_uploaded < max_files)
+		  AND (max_bytes IS NULL OR bytes_uploaded + $2 <= max_bytes)
+		RETURNING id
+	`, id, size).Scan(&got)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *pgUploadLinks) AddBytes(ctx context.Context, id string, size int64) error {
+	var got string
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE upload_links
+		SET bytes_uploaded = bytes_uploaded + $2
+		WHERE id = $1
+		  AND (max_bytes IS NULL OR bytes_uploaded + $2 <= max_bytes)
+		RETURNING id
+	`, id, size).Scan(&got)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *pgUploadLinks) Release(ctx context.Context, id string, size int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE upload_links
+		SET files_uploaded = GREATEST(files_uploaded - 1, 0), bytes_uploaded = GREATEST(bytes_uploaded - $2, 0)
+		WHERE id = $1
+	`, id, size)
+	return err
+}
+
+type pgAssets struct {
+	db *sql.DB
+}
+
+func (r *pgAssets) QueueDeletion(ctx context.Context, key string, attempts int, lastErr string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO pending_asset_deletions (storage_key, attempts, last_error)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (storage_key) DO UPDATE
+		SET attempts = pending_asset_deletions.attempts + EXCLUDED.attempts,
+		    last_error = EXCLUDED.last_error,
+		    updated_at = NOW()
+	`, key, attempts, lastErr)
+	return err
+}
+
+func (r *pgAssets) PendingDeletions(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT storage_key FROM pending_asset_deletions
+		ORDER BY updated_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *pgAssets) DeletionFailed(ctx context.Context, key string, lastErr string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE pending_asset_deletions
+		SET attempts = attempts + 1, last_error = $2, updated_at = NOW()
+		WHERE storage_key = $1
+	`, key, lastErr)
+	return err
+}
+
+func (r *pgAssets) DeletionDone(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM pending_asset_deletions WHERE storage_key = $1`, key)
+	return err
+}
+
+func (r *pgAssets) RecordAccountDeletion(ctx context.Context, userID string, report []byte) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO account_deletions (user_id, report) VALUES ($1, $2)`, userID, report)
+	return err
+}
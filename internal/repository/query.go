@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"Backend/internal/imaging"
+)
+
+// wallClockLayout formats TIMESTAMP WITHOUT TIME ZONE values such as captured_at.
+const wallClockLayout = "2006-01-02T15:04:05.999999999"
+
+// queryBuilder accumulates WHERE conditions and their positional arguments.
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+func newQueryBuilder(cond string, args ...any) *queryBuilder {
+	return &queryBuilder{conds: []string{cond}, args: args}
+}
+
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+// keyset restricts results to rows after the cursor in (sortExpr, id) order.
+func (b *queryBuilder) keyset(sortExpr, cast string, desc bool, after Cursor) {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+	b.where(fmt.Sprintf("(%s, id) %s (%s::%s, %s::uuid)", sortExpr, cmp, b.arg(after.Value), cast, b.arg(after.ID)))
+}
+
+func (b *queryBuilder) query(selectFrom, sortExpr string, desc bool, limit int) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	q := fmt.Sprintf("%s WHERE %s ORDER BY %s %s, id %s", selectFrom, strings.Join(b.conds, " AND "), sortExpr, dir, dir)
+	if limit > 0 {
+		q += " LIMIT " + strconv.Itoa(limit)
+	}
+	return q
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// withOriginal builds an image's variant map from its own URL and dimensions
+// plus the given resized variants.
+func withOriginal(img Image, variants []ImageVariant) map[string]ImageVariant {
+	out := map[string]ImageVariant{
+		imaging.Original: {Name: imaging.Original, StorageKey: img.StorageKey, URL: img.URL, Width: img.Width, Height: img.Height},
+	}
+	for _, v := range variants {
+		out[v.Name] = v
+	}
+	return out
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ImageSortValue returns img's value for the given sort option, encoded as a
+// Cursor value.
+func ImageSortValue(img Image, sort string) string {
+	switch sort {
+	case SortCaptured:
+		return capturedOrUploaded(img).Format(wallClockLayout)
+	case SortSize:
+		if img.SizeBytes == nil {
+			return "0"
+		}
+		return strconv.FormatInt(*img.SizeBytes, 10)
+	default:
+		return img.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// FolderSortValue returns f's value for the given sort option, encoded as a
+// Cursor value.
+func FolderSortValue(f Folder, sort string) string {
+	if sort == SortName {
+		return f.Name
+	}
+	return f.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// ValidImageSortValue reports whether value could have come from
+// ImageSortValue for the given sort option.
+func ValidImageSortValue(sort, value string) bool {
+	var err error
+	switch sort {
+	case SortCaptured:
+		_, err = time.Parse(wallClockLayout, value)
+	case SortSize:
+		_, err = strconv.ParseInt(value, 10, 64)
+	default:
+		_, err = time.Parse(time.RFC3339Nano, value)
+	}
+	return err == nil
+}
+
+// ValidFolderSortValue reports whether value could have come from
+// FolderSortValue for the given sort option.
+func ValidFolderSortValue(sort, value string) bool {
+	if sort == SortName {
+		// Postgres text can't hold NUL bytes or invalid UTF-8
+		return utf8.ValidString(value) && !strings.ContainsRune(value, 0)
+	}
+	_, err := time.Parse(time.RFC3339Nano, value)
+	return err == nil
+}
+
+// capturedOrUploaded is the time the captured sort orders by: the camera's
+// wall-clock capture time, falling back to the upload time in UTC.
+func capturedOrUploaded(img Image) time.Time {
+	if img.CapturedAt != nil {
+		return *img.CapturedAt
+	}
+	return img.CreatedAt.UTC()
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a row does not exist or is outside the caller's scope.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a unique value (e.g. an email) is already taken.
+	ErrConflict = errors.New("conflict")
+)
+
+type User struct {
+	ID           string
+	FirstName    string
+	LastName     string
+	Email        string
+	PasswordHash string
+	Role         string
+	QRCodeLink   string
+	QRCodeKey    string
+	// EmailVerifiedAt is nil until the user follows their verification link.
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time
+}
+
+type Folder struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ImageVariant is one rendition of an image (thumbnail, medium or original).
+// Dimensions are null for files that could not be decoded as images.
+type ImageVariant struct {
+	Name       string `json:"-"`
+	StorageKey string `json:"-"`
+	URL        string `json:"url"`
+	Width      *int   `json:"width"`
+	Height     *int   `json:"height"`
+	SizeBytes  int64  `json:"-"`
+}
+
+type Image struct {
+	ID       string                  `json:"id"`
+	UserID   string                  `json:"user_id"`
+	URL      string                  `json:"url"`
+	FolderID *string                 `json:"folder_id"`
+	Width    *int                    `json:"width"`
+	Height   *int                    `json:"height"`
+	Variants map[string]ImageVariant `json:"variants"`
+
+	SizeBytes    *int64     `json:"size_bytes"`
+	CapturedAt   *time.Time `json:"captured_at"`
+	CameraMake   *string    `json:"camera_make"`
+	CameraModel  *string    `json:"camera_model"`
+	GPSLatitude  *float64   `json:"gps_latitude"`
+	GPSLongitude *float64   `json:"gps_longitude"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	StorageKey        string          `json:"-"`
+	DeviceInfo        json.RawMessage `json:"-"`
+	DeletionTokenHash string          `json:"-"`
+	UploadLinkID      *string         `json:"-"`
+	Orientation       *int            `json:"-"`
+}
+
+// UploadLink is an opaque, revocable link that lets guests upload into a
+// user's account, optionally limited in time, file count and total size.
+type UploadLink struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	FolderID      *string    `json:"folder_id"`
+	Name          string     `json:"name"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	MaxFiles      *int64     `json:"max_files"`
+	MaxBytes      *int64     `json:"max_bytes"`
+	FilesUploaded int64      `json:"files_uploaded"`
+	BytesUploaded int64      `json:"bytes_uploaded"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	TokenHash     string     `json:"-"`
+}
+
+// Active reports whether the link can accept uploads at now.
+func (l UploadLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil &&
+		(l.StartsAt == nil || !l.StartsAt.After(now)) &&
+		(l.EndsAt == nil || l.EndsAt.After(now))
+}
+
+// PurgeResult lists what UserRepo.Purge removed, including the storage keys
+// that no longer have any row referencing them and must be deleted remotely.
+type PurgeResult struct {
+	ImagesDeleted  int
+	FoldersDeleted int
+	AssetKeys      []string
+	QRCodeKey      string
+}
+
+// Page position for keyset pagination: the sort value of the last row seen,
+// encoded as text, and its id as a tie-breaker.
+type Cursor struct {
+	Value string
+	ID    string
+}
+
+// Image sort options.
+const (
+	SortUploaded = "uploaded"
+	SortCaptured = "captured"
+	SortSize     = "size"
+)
+
+// Folder sort options.
+const (
+	SortCreated = "created"
+	SortName    = "name"
+)
+
+// ImageQuery selects a user's images. Zero-valued filters are ignored and a
+// zero Limit returns every match.
+type ImageQuery struct {
+	UserID       string
+	FolderID     string
+	Unfiled      bool
+	UploadedFrom *time.Time
+	UploadedTo   *time.Time
+	CapturedFrom *time.Time
+	CapturedTo   *time.Time
+	Device       string
+	UploadLinkID string
+	Sort         string
+	Desc         bool
+	After        *Cursor
+	Limit        int
+}
+
+// FolderQuery selects a user's folders. A zero Limit returns every match.
+type FolderQuery struct {
+	UserID string
+	Name   string
+	Sort   string
+	Desc   bool
+	After  *Cursor
+	Limit  int
+}
+
+type UserRepo interface {
+	Create(ctx context.Context, u User) error
+	GetByID(ctx context.Context, id string) (User, error)
+	GetByEmail(ctx context.Context, email string) (User, error)
+	UpdateProfile(ctx context.Context, id, firstName, lastName string) error
+	UpdatePassword(ctx context.Context, id, passwordHash string) error
+	// SetQRCode records the QR code image generated for the user.
+	SetQRCode(ctx context.Context, id, link, key string) error
+	// ListLegacyQRCodes returns up to limit users, ordered by id and after
+	// afterID, whose QR code predates upload links: they have a QR code but
+	// no upload link it could encode.
+	ListLegacyQRCodes(ctx context.Context, afterID string, limit int) ([]User, error)
+	// MarkEmailVerified verifies the user's address, provided it is still
+	// email, and reports whether it was unverified until now. It returns
+	// ErrNotFound if the user's email has since changed.
+	MarkEmailVerified(ctx context.Context, id, email string) (bool, error)
+	Delete(ctx context.Context, id string) error
+	// Purge deletes the user with all their images, folders, links and
+	// sessions atomically and reports which assets to delete from storage.
+	Purge(ctx context.Context, id string) (PurgeResult, error)
+}
+
+type FolderRepo interface {
+	Create(ctx context.Context, f Folder) (Folder, error)
+	List(ctx context.Context, q FolderQuery) ([]Folder, error)
+	BelongsTo(ctx context.Context, id, userID string) (bool, error)
+	Delete(ctx context.Context, id, userID string) error
+}
+
+type ImageRepo interface {
+	// Create stores an image together with its variants.
+	Create(ctx context.Context, img Image, variants []ImageVariant) (Image, error)
+	// List returns matching images with their variants attached.
+	List(ctx context.Context, q ImageQuery) ([]Image, error)
+	// Delete removes an image owned by userID and returns the storage keys
+	// that are no longer referenced by any image.
+	Delete(ctx context.Context, id, userID string) ([]string, error)
+	// DeleteWithToken is Delete scoped by a guest deletion token hash instead of an owner.
+	DeleteWithToken(ctx context.Context, id, tokenHash string) ([]string, error)
+	// Move refiles images into folderID ("" for unfiled). It fails with
+	// ErrNotFound, changing nothing, unless userID owns every image.
+	Move(ctx context.Context, userID string, ids []string, folderID string) ([]string, error)
+	// Copy duplicates images into folderID, sharing the source's assets.
+	Copy(ctx context.Context, userID string, ids []string, folderID string) ([]string, error)
+}
+
+type SessionRepo interface {
+	Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, error)
+	// Rotate swaps the refresh token of the session holding oldHash. A replayed
+	// rotated-out token revokes its session and returns ErrNotFound.
+	Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (userID, email, sessionID string, err error)
+	Active(ctx context.Context, id, userID string) (bool, error)
+	Revoke(ctx context.Context, id, userID string) error
+	// RevokeAll revokes every session of userID except exceptID ("" for none).
+	RevokeAll(ctx context.Context, userID, exceptID string) error
+}
+
+type PasswordResetRepo interface {
+	Create(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	// Redeem uses up the unexpired token with tokenHash, sets the owner's
+	// password and invalidates their other reset tokens, all atomically. It
+	// returns the owner's id, or ErrNotFound if the token is not usable.
+	Redeem(ctx context.Context, tokenHash, passwordHash string) (string, error)
+	// InvalidateAll marks every outstanding reset token of userID as used.
+	InvalidateAll(ctx context.Context, userID string) error
+}
+
+type UploadLinkRepo interface {
+	Create(ctx context.Context, l UploadLink) (UploadLink, error)
+	ListByUser(ctx context.Context, userID string) ([]UploadLink, error)
+	// GetActive returns the link for tokenHash if it is currently usable.
+	GetActive(ctx context.Context, tokenHash string) (UploadLink, error)
+	Revoke(ctx context.Context, id, userID string) error
+	// Reserve counts one file of size bytes against the link, failing with
+	// ErrConflict when that would exceed its limits.
+	Reserve(ctx context.Context, id string, size int64) error
+	// AddBytes counts size more bytes against a file already reserved,
+	// failing with ErrConflict when that would exceed the link's byte limit.
+	AddBytes(ctx context.Context, id string, size int64) error
+	Release(ctx context.Context, id string, size int64) error
+}
+
+// AssetRepo tracks remote asset clean-up.
+type AssetRepo interface {
+	QueueDeletion(ctx context.Context, key string, attempts int, lastErr string) error
+	PendingDeletions(ctx context.Context, limit int) ([]string, error)
+	DeletionFailed(ctx context.Context, key string, lastErr string) error
+	DeletionDone(ctx context.Context, key string) error
+	RecordAccountDeletion(ctx context.Context, userID string, report []byte) error
+}
+
+// Repos bundles every repository a handler may need.
+type Repos struct {
+	Users          UserRepo
+	Folders        FolderRepo
+	Images         ImageRepo
+	Sessions       SessionRepo
+	PasswordResets PasswordResetRepo
+	UploadLinks    UploadLinkRepo
+	Assets         AssetRepo
+}
@@ -2,26 +2,31 @@ package routes
 
 import (
 	"Backend/internal/handlers"
-	"Backend/internal/middleware"
 	"github.com/go-chi/chi/v5"
+	"net/http"
 )
 
-func RegisterAuthRoutes(r chi.Router) {
+func RegisterAuthRoutes(r chi.Router, h *handlers.Handler, auth func(http.Handler) http.Handler) {
 	// Public routes
-	r.Post("/signup", handlers.SignUpHandler)
-	r.Post("/login", handlers.LoginHandler)
-	r.Post("/forgotPassword", handlers.ForgotPasswordHandler)
-	r.Post("/resetPassword", handlers.ResetPasswordHandler)
+	r.Post("/signup", h.SignUpHandler)
+	r.Post("/login", h.LoginHandler)
+	r.Post("/refresh", h.RefreshHandler)
+	r.Post("/forgotPassword", h.ForgotPasswordHandler)
+	r.Post("/resetPassword", h.ResetPasswordHandler)
+	r.Post("/verifyEmail", h.VerifyEmailHandler)
 
 	// Protected routes
 	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware)
+		protected.Use(auth)
 
 		// Authenticated user data
-		protected.Get("/getUserData", handlers.GetUserHandler)
-		protected.Post("/changePassword", handlers.ChangePasswordHandler)
-		protected.Post("/changeUserProfile", handlers.UpdateUserProfileHandler)
-		protected.Delete("/deleteUser", handlers.DeleteUserHandler)
+		protected.Get("/getUserData", h.GetUserHandler)
+		protected.Post("/changePassword", h.ChangePasswordHandler)
+		protected.Post("/changeUserProfile", h.UpdateUserProfileHandler)
+		protected.Delete("/deleteUser", h.DeleteUserHandler)
+		protected.Post("/logout", h.LogoutHandler)
+		protected.Post("/logout-all", h.LogoutAllHandler)
+		protected.Post("/resendVerificationEmail", h.ResendVerificationEmailHandler)
 	})
 
 }
@@ -1,18 +1,19 @@
-package routes
-
-import (
-	"Backend/internal/handlers"
-	"Backend/internal/middleware"
-	"github.com/go-chi/chi/v5"
-)
-
-func RegisterFolderRoutes(r chi.Router) {
-	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware)
-		
-		// Folder management endpoints
-		protected.Post("/folders", handlers.CreateFolderHandler)
-		protected.Get("/folders", handlers.GetFoldersHandler)
-		protected.Delete("/folders/{id}", handlers.DeleteFolderHandler)
-	})
-} 
\ No newline at end of file
+package routes
+
+import (
+	"Backend/internal/handlers"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+func RegisterFolderRoutes(r chi.Router, h *handlers.Handler, auth func(http.Handler) http.Handler) {
+	r.Group(func(protected chi.Router) {
+		protected.Use(auth)
+
+		// Folder management endpoints
+		protected.Post("/folders", h.CreateFolderHandler)
+		protected.Get("/folders", h.GetFoldersHandler)
+		protected.Delete("/folders/{id}", h.DeleteFolderHandler)
+		protected.Get("/folders/{id}/images", h.GetFolderImagesHandler)
+	})
+}
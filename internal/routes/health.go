@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"Backend/internal/handlers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterHealthRoutes mounts the probes used by the orchestrator. They are
+// public and live outside /api.
+func RegisterHealthRoutes(r chi.Router, h *handlers.Handler) {
+	r.Get("/healthz", h.HealthzHandler)
+	r.Get("/readyz", h.ReadyzHandler)
+}
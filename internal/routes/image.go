@@ -2,18 +2,29 @@ package routes
 
 import (
 	"Backend/internal/handlers"
-	"Backend/internal/middleware"
-	
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterImageRoutes(r chi.Router) {
-	// Public route for adding an image
-	r.Post("/upload/files", handlers.AddImageHandler)
-	r.Delete("/deleteImages/{id}", handlers.DeleteImageHandler)
+func RegisterImageRoutes(r chi.Router, h *handlers.Handler, auth func(http.Handler) http.Handler) {
+	// Public route for adding an image through an upload link
+	r.Post("/upload/files", h.AddImageHandler)
+	r.Get("/upload/link", h.GetPublicUploadLinkHandler)
+	// Guests can delete their own uploads with the per-upload deletion token
+	r.Delete("/upload/files/{id}", h.GuestDeleteImageHandler)
 
 	r.Group(func(protected chi.Router) {
-		protected.Use(middleware.AuthMiddleware)
-		protected.Get("/images", handlers.GetImagesHandler)
+		protected.Use(auth)
+		protected.Get("/images", h.GetImagesHandler)
+		protected.Get("/images/timeline", h.GetImageTimelineHandler)
+		protected.Delete("/deleteImages/{id}", h.DeleteImageHandler)
+		protected.Post("/images/move", h.MoveImagesHandler)
+		protected.Post("/images/copy", h.CopyImagesHandler)
+
+		// Guest upload links
+		protected.Post("/upload-links", h.CreateUploadLinkHandler)
+		protected.Get("/upload-links", h.GetUploadLinksHandler)
+		protected.Delete("/upload-links/{id}", h.RevokeUploadLinkHandler)
 	})
 }
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// Cloudinary stores files as Cloudinary assets. Object keys are Cloudinary public_ids.
+type Cloudinary struct {
+	cld *cloudinary.Cloudinary
+}
+
+// cloudinaryDeliveryPrefix matches a Cloudinary delivery URL up to the public
+// id: the cloud name, resource type, "upload" and an optional version.
+var cloudinaryDeliveryPrefix = regexp.MustCompile(`^https?://res\.cloudinary\.com/[^/]+/[^/]+/upload/(v[0-9]+/)?`)
+
+// CloudinaryPublicID recovers the key of an asset from its Cloudinary
+// delivery URL, for rows stored before keys were recorded. It reports false
+// for URLs that are not Cloudinary delivery URLs.
+func CloudinaryPublicID(url string) (string, bool) {
+	loc := cloudinaryDeliveryPrefix.FindStringIndex(url)
+	if loc == nil {
+		return "", false
+	}
+	id := url[loc[1]:]
+	id = strings.TrimSuffix(id, path.Ext(id))
+	return id, id != ""
+}
+
+func NewCloudinary(cloudName, apiKey, apiSecret string) (*Cloudinary, error) {
+	if cloudName == "" || apiKey == "" || apiSecret == "" {
+		return nil, fmt.Errorf("missing Cloudinary cloud name or credentials")
+	}
+
+	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Cloudinary: %v", err)
+	}
+	cld.Config.URL.Secure = true
+
+	return &Cloudinary{cld: cld}, nil
+}
+
+func (c *Cloudinary) Put(ctx context.Context, key string, r io.Reader, contentType string) (Object, error) {
+	uniqueFilename := false
+	overwrite := false
+	uploadParams := uploader.UploadParams{
+		ResourceType:   "auto",
+		PublicID:       strings.TrimSuffix(key, path.Ext(key)),
+		UniqueFilename: &uniqueFilename,
+		Overwrite:      &overwrite,
+	}
+
+	resp, err := c.cld.Upload.Upload(ctx, r, uploadParams)
+	if err != nil {
+		return Object{}, err
+	}
+	if resp.Error.Message != "" {
+		return Object{}, fmt.Errorf("cloudinary upload failed: %s", resp.Error.Message)
+	}
+	if resp.SecureURL == "" {
+		return Object{}, fmt.Errorf("SecureURL is empty")
+	}
+
+	return Object{
+		Key:  resp.PublicID,
+		URL:  resp.SecureURL,
+		Size: int64(resp.Bytes),
+	}, nil
+}
+
+func (c *Cloudinary) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	url, err := c.SignedURL(ctx, key, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cloudinary get %s: unexpected status %d", key, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Delete destroys the asset. Uploads use ResourceType "auto", so the asset may
+// have been stored as an image, video or raw file; each is tried in turn.
+func (c *Cloudinary) Delete(ctx context.Context, key string) error {
+	invalidate := true
+	for _, resourceType := range []string{"image", "video", "raw"} {
+		resp, err := c.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+			PublicID:     key,
+			ResourceType: resourceType,
+			Invalidate:   &invalidate,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.Error.Message != "" {
+			return fmt.Errorf("cloudinary destroy failed: %s", resp.Error.Message)
+		}
+		if resp.Result == "ok" {
+			return nil
+		}
+	}
+	return nil
+}
+
+// SignedURL returns a signed delivery URL for the asset. Cloudinary delivery
+// signatures do not expire, so ttl is ignored.
+func (c *Cloudinary) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	img, err := c.cld.Image(key)
+	if err != nil {
+		return "", err
+	}
+	img.Config.URL.SignURL = true
+	return img.String()
+}
+
+// Ping calls the admin API's ping endpoint, which is rate limited; callers
+// polling it should cache the result.
+func (c *Cloudinary) Ping(ctx context.Context) error {
+	resp, err := c.cld.Admin.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	if resp.Error.Message != "" {
+		return fmt.Errorf("cloudinary ping failed: %s", resp.Error.Message)
+	}
+	return nil
+}
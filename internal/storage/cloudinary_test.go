@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestCloudinaryPublicID(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   string
+		wantOK bool
+	}{
+		{"https://res.cloudinary.com/demo/image/upload/v1700000000/4f1c.png", "4f1c", true},
+		{"https://res.cloudinary.com/demo/image/upload/qrcodes/4f1c/ab.png", "qrcodes/4f1c/ab", true},
+		{"http://res.cloudinary.com/demo/video/upload/v1/clip.mp4", "clip", true},
+		{"https://res.cloudinary.com/demo/image/upload/", "", false},
+		{"https://cdn.example.com/images/a.png", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := CloudinaryPublicID(tt.url)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("CloudinaryPublicID(%q) = %q, %v; want %q, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores files on the local filesystem. It is meant for development,
+// CI and single-node self-hosted deployments; files are served by ServeHTTP.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if dir == "" {
+		dir = "uploads"
+	}
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/files"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &Local{dir: dir, baseURL: strings.TrimRight(baseURL, "/"), secret: secret}, nil
+}
+
+// path resolves key inside the storage directory, rejecting keys that would escape it.
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) (Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return Object{}, err
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return Object{}, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(p)
+		return Object{}, err
+	}
+
+	return Object{Key: key, URL: l.baseURL + "/" + key, Size: n}, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Ping checks that the storage directory is writable.
+func (l *Local) Ping(ctx context.Context) error {
+	f, err := os.CreateTemp(l.dir, ".ping-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// SignedURL returns a URL carrying an expiry and an HMAC over key and expiry.
+// The signing secret is generated per process, so links do not survive restarts.
+func (l *Local) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", l.sign(key, expires))
+	return l.baseURL + "/" + key + "?" + q.Encode(), nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves stored files. Requests carrying a signature are verified
+// and rejected once expired; unsigned requests are served as public URLs.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+
+	if sig := r.URL.Query().Get("signature"); sig != "" {
+		expires := r.URL.Query().Get("expires")
+		exp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || time.Now().Unix() > exp || !hmac.Equal([]byte(sig), []byte(l.sign(key, expires))) {
+			http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+			return
+		}
+	}
+
+	p, err := l.path(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	// Directory listings would reveal every key stored below them
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalServesFilesButNotDirectories(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://files.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Put(context.Background(), "images/user-1/a.jpg", strings.NewReader("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/images/user-1/a.jpg", http.StatusOK, "jpeg"},
+		{"/images/user-1/", http.StatusNotFound, ""},
+		{"/images/user-1", http.StatusNotFound, ""},
+		{"/images/", http.StatusNotFound, ""},
+		{"/images/user-1/missing.jpg", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.wantStatus)
+		}
+		if tt.wantBody != "" && string(body) != tt.wantBody {
+			t.Errorf("GET %s: body %q, want %q", tt.path, body, tt.wantBody)
+		}
+		if strings.Contains(string(body), "a.jpg") && tt.wantStatus != http.StatusOK {
+			t.Errorf("GET %s: response lists stored files: %q", tt.path, body)
+		}
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://files.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	obj, err := l.Put(ctx, "images/u/a.png", strings.NewReader("png"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Key != "images/u/a.png" || obj.URL != "http://files.test/images/u/a.png" || obj.Size != 3 {
+		t.Fatalf("Put = %+v", obj)
+	}
+	// Keys are never overwritten
+	if _, err := l.Put(ctx, "images/u/a.png", strings.NewReader("other"), "image/png"); err == nil {
+		t.Fatal("Put overwrote an existing key")
+	}
+
+	rc, err := l.Get(ctx, "images/u/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "png" {
+		t.Fatalf("Get = %q", body)
+	}
+
+	if err := l.Delete(ctx, "images/u/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "images/u/a.png"); err == nil {
+		t.Fatal("Get found a deleted key")
+	}
+	// Deleting a missing key is not an error
+	if err := l.Delete(ctx, "images/u/a.png"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestLocalKeysStayInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(dir+"/store", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Put(context.Background(), "../../escaped.txt", strings.NewReader("x"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(context.Background(), "escaped.txt"); err != nil {
+		t.Fatalf("key was not kept inside the storage directory: %v", err)
+	}
+	if _, err := l.Put(context.Background(), "/", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Fatal("Put accepted an empty key")
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://files.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := l.Put(ctx, "a.jpg", strings.NewReader("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(rawURL string) int {
+		rec := httptest.NewRecorder()
+		l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(rawURL, "http://files.test"), nil))
+		return rec.Code
+	}
+
+	valid, _ := l.SignedURL(ctx, "a.jpg", time.Minute)
+	if status := get(valid); status != http.StatusOK {
+		t.Errorf("valid signature: status %d", status)
+	}
+	expired, _ := l.SignedURL(ctx, "a.jpg", -time.Minute)
+	if status := get(expired); status != http.StatusForbidden {
+		t.Errorf("expired signature: status %d", status)
+	}
+	// A signature only covers the key it was made for
+	if status := get(strings.Replace(valid, "a.jpg", "b.jpg", 1)); status != http.StatusForbidden {
+		t.Errorf("signature for another key: status %d", status)
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options configures an S3-compatible backend (AWS S3, MinIO, R2, ...).
+type S3Options struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl"`
+	// PublicURL is the base URL objects are publicly reachable at. It is
+	// required: the URLs Put returns are stored, so they must not expire.
+	PublicURL string `yaml:"public_url"`
+}
+
+// S3 stores files in an S3-compatible bucket.
+type S3 struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// streamPartSize is the multipart chunk size used for uploads of unknown
+// length. minio-go otherwise sizes parts for a 5 TiB object and allocates
+// a buffer of that part size on every Put.
+const streamPartSize = 16 << 20
+
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" || opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("missing S3 endpoint, bucket or credentials")
+	}
+	if opts.PublicURL == "" {
+		return nil, fmt.Errorf("missing S3 public URL")
+	}
+
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize S3 client: %v", err)
+	}
+
+	return &S3{
+		client:    client,
+		bucket:    opts.Bucket,
+		publicURL: strings.TrimRight(opts.PublicURL, "/"),
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) (Object, error) {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+
+	// In-memory readers (variants, QR codes) know their length and go up in
+	// a single request; streamed originals are sent in bounded parts
+	size := int64(-1)
+	if l, ok := r.(interface{ Len() int }); ok {
+		size = int64(l.Len())
+	} else {
+		opts.PartSize = streamPartSize
+	}
+
+	info, err := s.client.PutObject(ctx, s.bucket, key, r, size, opts)
+	if err != nil {
+		return Object{}, err
+	}
+
+	return Object{Key: info.Key, URL: s.publicURL + "/" + key, Size: info.Size}, nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat surfaces a missing key before the caller reads.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Ping checks that the bucket exists and the credentials can see it.
+func (s *S3) Ping(ctx context.Context) error {
+	ok, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fakeS3 serves the subset of the S3 API the backend uses for small objects,
+// keeping objects of a single bucket in memory.
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	// parts holds the parts of unfinished multipart uploads by upload id
+	parts map[string][][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `<Error><Code>NoSuchBucket</Code><Message>no such bucket</Message></Error>`)
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := strconv.Itoa(len(f.parts) + 1)
+		f.parts[id] = nil
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		body := readBody(r)
+		f.parts[q.Get("uploadId")] = append(f.parts[q.Get("uploadId")], body)
+		w.Header().Set("ETag", `"part"`)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		f.objects[key] = bytes.Join(f.parts[q.Get("uploadId")], nil)
+		delete(f.parts, q.Get("uploadId"))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`, bucket, key)
+	case r.Method == http.MethodPut:
+		f.objects[key] = readBody(r)
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>no such key</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(body))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readBody returns a request body, decoding the aws-chunked encoding minio-go
+// uses for signed streaming uploads over plain HTTP.
+func readBody(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return body
+	}
+	var out []byte
+	for {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return out
+		}
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			return out
+		}
+		out = append(out, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+}
+
+func newTestS3(t *testing.T) (*S3, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{bucket: "photos", objects: map[string][]byte{}, parts: map[string][][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	s, err := NewS3(S3Options{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Region:    "us-east-1",
+		Bucket:    "photos",
+		AccessKey: "key",
+		SecretKey: "secret",
+		PublicURL: "https://cdn.example.com/photos/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	s, fake := newTestS3(t)
+	ctx := context.Background()
+
+	// Streamed originals don't know their length; variants do
+	inputs := map[string]io.Reader{
+		"images/u/a.png":   iotest.OneByteReader(strings.NewReader("streamed")),
+		"images/u/a_m.jpg": bytes.NewReader([]byte("variant")),
+	}
+	for key, r := range inputs {
+		obj, err := s.Put(ctx, key, r, "image/png")
+		if err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+		// Stored URLs must be permanent, so they come from the public URL
+		if obj.URL != "https://cdn.example.com/photos/"+key {
+			t.Errorf("Put %s: URL %q", key, obj.URL)
+		}
+	}
+	if got := string(fake.objects["images/u/a.png"]); got != "streamed" {
+		t.Fatalf("stored %q", got)
+	}
+
+	rc, err := s.Get(ctx, "images/u/a_m.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "variant" {
+		t.Fatalf("Get = %q", body)
+	}
+
+	if err := s.Delete(ctx, "images/u/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "images/u/a.png"); err == nil {
+		t.Fatal("Get found a deleted key")
+	}
+}
+
+func TestS3Ping(t *testing.T) {
+	s, fake := newTestS3(t)
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.bucket = "other"
+	fake.mu.Unlock()
+	if err := s.Ping(context.Background()); err == nil {
+		t.Fatal("Ping succeeded without the bucket")
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Object describes a file that has been written to a storage backend.
+type Object struct {
+	Key  string `json:"key"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// Storage is implemented by every backend that can hold uploaded files.
+type Storage interface {
+	// Put writes the contents of r under key and returns the stored object.
+	// Backends may rewrite the key (e.g. Cloudinary assigns its own public_id),
+	// so callers must persist the returned Object.Key, not the one they passed in.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (Object, error)
+	// Get opens the object stored under key. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to key for roughly ttl.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Ping checks that the backend is reachable and usable.
+	Ping(ctx context.Context) error
+}
+
+// Config selects and configures a storage backend.
+type Config struct {
+	// Backend is "cloudinary" (the default), "local" or "s3" ("minio").
+	Backend    string           `yaml:"backend"`
+	Cloudinary CloudinaryConfig `yaml:"cloudinary"`
+	Local      LocalConfig      `yaml:"local"`
+	S3         S3Options        `yaml:"s3"`
+}
+
+type CloudinaryConfig struct {
+	CloudName string `yaml:"cloud_name"`
+	APIKey    string `yaml:"api_key"`
+	APISecret string `yaml:"api_secret"`
+}
+
+type LocalConfig struct {
+	Dir     string `yaml:"dir"`
+	BaseURL string `yaml:"base_url"`
+}
+
+// Name is the canonical name of the configured backend: "cloudinary",
+// "local" or "s3". Unknown names are returned as given.
+func (c Config) Name() string {
+	switch backend := strings.ToLower(strings.TrimSpace(c.Backend)); backend {
+	case "":
+		return "cloudinary"
+	case "minio":
+		return "s3"
+	default:
+		return backend
+	}
+}
+
+// New builds the backend named by cfg.Backend.
+func New(cfg Config) (Storage, error) {
+	switch backend := cfg.Name(); backend {
+	case "cloudinary":
+		return NewCloudinary(cfg.Cloudinary.CloudName, cfg.Cloudinary.APIKey, cfg.Cloudinary.APISecret)
+	case "local":
+		return NewLocal(cfg.Local.Dir, cfg.Local.BaseURL)
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
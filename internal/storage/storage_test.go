@@ -0,0 +1,39 @@
+package storage
+
+import "testing"
+
+func TestConfigName(t *testing.T) {
+	tests := map[string]string{
+		"":           "cloudinary",
+		"Cloudinary": "cloudinary",
+		" local ":    "local",
+		"minio":      "s3",
+		"S3":         "s3",
+		"gcs":        "gcs",
+	}
+	for backend, want := range tests {
+		if got := (Config{Backend: backend}).Name(); got != want {
+			t.Errorf("Name() for %q = %q, want %q", backend, got, want)
+		}
+	}
+}
+
+func TestNewSelectsBackend(t *testing.T) {
+	s, err := New(Config{Backend: "local", Local: LocalConfig{Dir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*Local); !ok {
+		t.Fatalf("New(local) = %T", s)
+	}
+
+	if _, err := New(Config{Backend: "gcs"}); err == nil {
+		t.Error("New accepted an unknown backend")
+	}
+	if _, err := New(Config{Backend: "cloudinary"}); err == nil {
+		t.Error("New(cloudinary) accepted missing credentials")
+	}
+	if _, err := New(Config{Backend: "s3", S3: S3Options{Endpoint: "s3.test", Bucket: "b", AccessKey: "k", SecretKey: "s"}}); err == nil {
+		t.Error("New(s3) accepted a missing public URL")
+	}
+}
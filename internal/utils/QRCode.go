@@ -1,34 +1,29 @@
 package utils
 
 import (
+	"Backend/internal/storage"
+	"bytes"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
+	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 )
 
-func GenerateQRCode(id string) (string, error) {
-	content := fmt.Sprintf(`%s/upload/id=%s`,os.Getenv("FRONTEND_API"),id)
-	savePath := fmt.Sprintf("%s.png", id)
-
-	// Ensure the directory exists
-	if err := os.MkdirAll(filepath.Dir(savePath), os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create directory: %v", err)
-	}
-
-	err := qrcode.WriteFile(content, qrcode.Medium, 256, savePath)
+// GenerateQRCode renders content (the user's guest upload link) as a QR code
+// and stores it under a fresh key below the user's id, so a replacement never
+// collides with the code it replaces. The returned object's Key must be kept
+// so the asset can be deleted later.
+func GenerateQRCode(ctx context.Context, store storage.Storage, id, content string) (storage.Object, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, 256)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate QR code: %v", err)
+		return storage.Object{}, fmt.Errorf("failed to generate QR code: %v", err)
 	}
 
-	url, err := UploadToCloudinary(savePath)
-	if err != nil{
-		return "", fmt.Errorf("failed to upload files at cloudinary %v",err)
+	obj, err := store.Put(ctx, fmt.Sprintf("qrcodes/%s/%s.png", id, uuid.New().String()), bytes.NewReader(png), "image/png")
+	if err != nil {
+		return storage.Object{}, fmt.Errorf("failed to upload QR code to storage: %v", err)
 	}
 
-	// Optional: delete the local file to save disk space
-	_ = os.Remove(savePath)
-
-	return url, nil
+	return obj, nil
 }
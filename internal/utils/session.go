@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// AccessTokenTTL is the lifetime of the JWT sent on every API call.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a session can be kept alive by refreshing.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// GenerateAccessToken issues a short-lived JWT bound to a session.
+func GenerateAccessToken(secret []byte, userID, email, sessionID string) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"email":   email,
+		"sid":     sessionID,
+		"exp":     time.Now().Add(AccessTokenTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
+
+// GenerateOpaqueToken returns a random URL-safe token suitable for refresh,
+// deletion and upload-link tokens. Only its HashToken digest should be stored.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 digest under which a token is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,35 +1,50 @@
 package utils
 
 import (
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+// PasswordResetTTL is how long a password reset link stays valid. Reset
+// tokens are opaque (see GenerateOpaqueToken), stored hashed and single-use.
+const PasswordResetTTL = 15 * time.Minute
 
-func GenerateResetToken(userID string) (string, error) {
+// EmailVerificationTTL is how long a verification link stays valid.
+const EmailVerificationTTL = 24 * time.Hour
+
+// GenerateEmailVerificationToken signs a token proving that whoever holds it
+// received mail at email. The purpose claim keeps it from being accepted
+// anywhere else.
+func GenerateEmailVerificationToken(secret []byte, userID, email string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+		"email":   email,
+		"purpose": "verify_email",
+		"exp":     time.Now().Add(EmailVerificationTTL).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(secret)
 }
 
-func ParseResetToken(tokenStr string) (string, error) {
+// ParseEmailVerificationToken returns the user id and address a verification
+// token was issued for.
+func ParseEmailVerificationToken(secret []byte, tokenStr string) (userID, email string, err error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil || !token.Valid {
-		return "", err
+		return "", "", jwt.ErrTokenInvalidClaims
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, _ := claims["user_id"].(string)
-		return userID, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "verify_email" {
+		return "", "", jwt.ErrTokenInvalidClaims
 	}
-
-	return "", jwt.ErrTokenMalformed
+	userID, _ = claims["user_id"].(string)
+	email, _ = claims["email"].(string)
+	if userID == "" || email == "" {
+		return "", "", jwt.ErrTokenInvalidClaims
+	}
+	return userID, email, nil
 }
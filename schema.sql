@@ -1,32 +0,0 @@
--- Create images table for file uploads
-CREATE TABLE IF NOT EXISTS images (
-    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
-    user_id VARCHAR(255) NOT NULL,
-    image_url TEXT NOT NULL,
-    device_info JSONB,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-);
-
--- Create folders table for folder management
-CREATE TABLE IF NOT EXISTS folders (
-    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
-    user_id VARCHAR(255) NOT NULL,
-    name VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-);
-
--- Create index on user_id for faster queries
-CREATE INDEX IF NOT EXISTS idx_images_user_id ON images(user_id);
-CREATE INDEX IF NOT EXISTS idx_folders_user_id ON folders(user_id);
-
--- Create index on created_at for sorting
-CREATE INDEX IF NOT EXISTS idx_images_created_at ON images(created_at);
-CREATE INDEX IF NOT EXISTS idx_folders_created_at ON folders(created_at);
-
--- Grant necessary permissions (adjust as needed for your database setup)
--- GRANT ALL PRIVILEGES ON TABLE images TO your_user;
--- GRANT ALL PRIVILEGES ON TABLE folders TO your_user;
--- GRANT USAGE, SELECT ON SEQUENCE images_id_seq TO your_user;
--- GRANT USAGE, SELECT ON SEQUENCE folders_id_seq TO your_user; 
\ No newline at end of file